@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grafana/escalation-scheduler/pkg/icassigner"
+)
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "validate":
+		return runValidate(args, os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q, available commands: validate", name)
+	}
+}
+
+// runValidate checks a local config strictly and reports findings of the lint rules, e.g. teams without members.
+// It fails if the config is invalid or anything was found, so it can be used in CI.
+func runValidate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	cfgPath := fs.String("config", ".github/escalation-assignment.yaml", "Path to the ic-assignment config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*cfgPath)
+	if err != nil {
+		return fmt.Errorf("unable to read config, due %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := icassigner.ParseConfig(f)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Extends != "" || len(cfg.Include) > 0 {
+		fmt.Fprintln(out, "extends and include are not resolved, only this file is checked")
+	}
+
+	findings := cfg.Lint()
+	for _, f := range findings {
+		fmt.Fprintln(out, f)
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d problems in %s", len(findings), *cfgPath)
+	}
+
+	fmt.Fprintf(out, "%s is valid\n", *cfgPath)
+	return nil
+}
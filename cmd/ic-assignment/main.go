@@ -18,69 +18,328 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"time"
 
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/go-github/github"
 	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
 	"github.com/grafana/escalation-scheduler/pkg/icassigner"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/logging"
+	"github.com/grafana/escalation-scheduler/pkg/redact"
 )
 
+const (
+	// modeAssign assigns the issue or pull request of the triggering event.
+	modeAssign = "assign"
+
+	// modeEscalate escalates unacknowledged issues of the repository, meant to run on a schedule.
+	modeEscalate = "escalate"
+
+	// modeRebalance moves recently assigned issues away from unavailable members, meant to run on a schedule.
+	modeRebalance = "rebalance"
+
+	// modeCheckConfig checks changes of the config file by pull requests and reports the result as check run.
+	modeCheckConfig = "check-config"
+)
+
+const defaultCfgPath = "./github/escalation-assignment.yaml"
+
 func main() {
+	// Without any arguments we run as github action, otherwise the first argument selects a local subcommand.
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger, err := newLogger()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	mode := githubaction.GetInputOrDefault("mode", modeAssign)
+
+	switch mode {
+	case modeAssign:
+		err = runAssign(logger)
+	case modeEscalate:
+		err = runEscalate(logger)
+	case modeRebalance:
+		err = runRebalance(logger)
+	case modeCheckConfig:
+		err = runCheckConfig(logger)
+	default:
+		err = fmt.Errorf("unknown mode %q, expected %q, %q, %q or %q", mode, modeAssign, modeEscalate, modeRebalance, modeCheckConfig)
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "action failed", "mode", mode, "err", err)
+		os.Exit(1)
+	}
+}
+
+// newLogger creates the logger configured by the log-format, log-level and log-redaction inputs.
+func newLogger() (log.Logger, error) {
+	redactor, err := redact.New(githubaction.GetInputOrDefault("log-redaction", redact.ModeSecrets))
+	if err != nil {
+		return nil, err
+	}
+	redact.SetDefault(redactor)
+
+	logger, err := logging.New(os.Stderr, githubaction.GetInputOrDefault("log-format", logging.FormatLogfmt), githubaction.GetInputOrDefault("log-level", logging.LevelInfo))
+	if err != nil {
+		return nil, err
+	}
+
+	return redactor.Logger(logger), nil
+}
+
+func runAssign(logger log.Logger) error {
 	actionCtx, err := githubaction.LoadContext()
 	if err != nil {
-		log.Fatalf("Unable to load github context, due: %v", err)
+		return fmt.Errorf("unable to load github context, due %w", err)
 	}
 
-	if actionCtx.Issue == nil {
-		log.Fatal("Can not be used without an issue")
+	if actionCtx.Issue.Number == 0 {
+		return errors.New("can not be used without an issue or pull request")
 	}
 
-	if actionCtx.Issue.State == nil || *actionCtx.Issue.State != "open" {
-		log.Fatalf("Only works on currently open issues, but found %q. Stopping...", *actionCtx.Issue.State)
+	// comments only trigger a run if they contain a command
+	var command *icassigner.Command
+	if actionCtx.EventName == githubaction.EventIssueComment {
+		name, args, ok := actionCtx.Command()
+		if actionCtx.Action != "created" || !ok {
+			level.Info(logger).Log("msg", "comment doesn't contain a command, stopping")
+			return nil
+		}
+
+		// a retriage runs as if the issue was just opened, all other commands are handled by the action
+		if name != githubaction.CommandRetriage {
+			command = &icassigner.Command{Name: name, Args: args, Sender: actionCtx.Sender, CommentID: actionCtx.Comment.GetID()}
+		}
 	}
 
-	owner, repo, sha, err := githubaction.Repository()
+	if actionCtx.Issue.State != "open" {
+		return fmt.Errorf("only works on currently open issues, but found %q", actionCtx.Issue.State)
+	}
+
+	ctx := context.Background()
+
+	client, cfg, err := loadConfig(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	dryRun := githubaction.GetInputOrDefault("dry-run", "true") != "false"
+
+	labelsList := githubaction.GetInputOrDefault("labels", "")
+
+	action, err := newAction(client, cfg, logger)
 	if err != nil {
-		log.Fatalf("Unable to identify current github repo, due: %v", err)
+		return err
 	}
 
-	cfgPath := githubaction.GetInputOrDefault("cfg-path", "./github/escalation-assignment.yaml")
+	if command != nil {
+		err = action.RunCommand(ctx, actionCtx.Issue, *command, dryRun)
+	} else {
+		err = action.Run(ctx, actionCtx.Issue, labelsList, dryRun)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to run action, due %w", err)
+	}
+	return nil
+}
 
+func runEscalate(logger log.Logger) error {
 	ctx := context.Background()
 
-	client, err := githubaction.NewGithubClientFromEnv()
+	client, cfg, err := loadConfig(ctx, logger)
 	if err != nil {
-		log.Fatalf("Unable to create github client: %v", err)
+		return err
 	}
 
-	cfgReader, err := icassigner.FetchConfig(ctx, client, owner, repo, sha, cfgPath)
+	owner, repo, _, err := githubaction.Repository()
 	if err != nil {
-		log.Fatalf("Unable to get config: %v", err)
+		return fmt.Errorf("unable to identify current github repo, due %w", err)
 	}
 
-	cfg, err := icassigner.ParseConfig(cfgReader)
+	dryRun := githubaction.GetInputOrDefault("dry-run", "true") != "false"
+
+	action, err := newAction(client, cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	err = action.Escalate(ctx, owner, repo, time.Now(), dryRun)
 	if err != nil {
-		log.Fatalf("Unable to parse config: %v", err)
+		return fmt.Errorf("unable to escalate issues, due %w", err)
 	}
+	return nil
+}
+
+func runRebalance(logger log.Logger) error {
+	ctx := context.Background()
 
-	if actionCtx.Issue == nil {
-		log.Fatalf("Unable to run without an issue set")
+	client, cfg, err := loadConfig(ctx, logger)
+	if err != nil {
+		return err
 	}
 
-	if actionCtx.Issue.Number == nil {
-		log.Fatalf("No issue number is set")
+	owner, repo, _, err := githubaction.Repository()
+	if err != nil {
+		return fmt.Errorf("unable to identify current github repo, due %w", err)
 	}
 
 	dryRun := githubaction.GetInputOrDefault("dry-run", "true") != "false"
 
-	labelsList := githubaction.GetInputOrDefault("labels", "")
+	action, err := newAction(client, cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	report, err := action.Rebalance(ctx, owner, repo, time.Now(), dryRun)
+	if len(report) == 0 {
+		level.Info(logger).Log("msg", "no issues need to be rebalanced")
+	}
+
+	msg := "moved issue"
+	if dryRun {
+		msg = "proposed move (dry-run)"
+	}
+	for _, m := range report {
+		level.Info(logger).Log("msg", msg, "issue", m.Number, "title", redact.Personal(m.Title), "team", m.Team, "from", m.From, "to", m.To)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to rebalance issues, due %w", err)
+	}
+	return nil
+}
+
+func runCheckConfig(logger log.Logger) error {
+	actionCtx, err := githubaction.LoadContext()
+	if err != nil {
+		return fmt.Errorf("unable to load github context, due %w", err)
+	}
+
+	if actionCtx.Issue.HeadSHA == "" {
+		return fmt.Errorf("mode %q can only be used on pull_request events", modeCheckConfig)
+	}
+
+	owner, repo, _, err := githubaction.Repository()
+	if err != nil {
+		return fmt.Errorf("unable to identify current github repo, due %w", err)
+	}
+
+	cfgPath := path.Clean(githubaction.GetInputOrDefault("cfg-path", defaultCfgPath))
+
+	client, err := githubaction.NewGithubClientFromEnv()
+	if err != nil {
+		return fmt.Errorf("unable to create github client, due %w", err)
+	}
+
+	ctx := context.Background()
+
+	files, err := issue.ListChangedFiles(ctx, client, owner, repo, actionCtx.Issue.Number)
+	if err != nil {
+		return fmt.Errorf("unable to list changed files, due %w", err)
+	}
+
+	raw, err := icassigner.FetchFile(ctx, client, owner, repo, actionCtx.Issue.HeadSHA, cfgPath)
+	if err != nil {
+		return fmt.Errorf("unable to get config, due %w", err)
+	}
+
+	if !slices.Contains(files, cfgPath) {
+		// changes of configs included by the config affect it as well, broken includes are reported by the check
+		included, err := icassigner.IncludedFiles(ctx, client, owner, repo, actionCtx.Issue.HeadSHA, cfgPath, raw)
+		if err == nil && !slices.ContainsFunc(included, func(p string) bool { return slices.Contains(files, p) }) {
+			level.Info(logger).Log("msg", "neither the config nor its includes are changed by the pull request, stopping", "path", cfgPath)
+			return nil
+		}
+	}
+
+	result, err := icassigner.CheckConfig(ctx, client, owner, repo, actionCtx.Issue.HeadSHA, cfgPath, raw)
+	if err != nil {
+		return fmt.Errorf("unable to check config, due %w", err)
+	}
+
+	for _, f := range result.Errors {
+		level.Error(logger).Log("msg", "config error", "line", f.Line, "finding", f.String())
+	}
+	for _, f := range result.Warnings {
+		level.Warn(logger).Log("msg", "config warning", "line", f.Line, "finding", f.String())
+	}
 
-	action := &icassigner.Action{
-		Client: client,
-		Config: cfg,
+	if githubaction.GetInputOrDefault("dry-run", "true") != "false" {
+		level.Info(logger).Log("msg", "exiting because dry-run is enabled")
+		return nil
 	}
 
-	err = action.Run(ctx, actionCtx, labelsList, dryRun)
+	err = icassigner.PublishCheckRun(ctx, client, owner, repo, actionCtx.Issue.HeadSHA, cfgPath, result)
 	if err != nil {
-		log.Fatalf("Unable to run action: %v", err)
+		return fmt.Errorf("unable to publish check run, due %w", err)
 	}
+	return nil
+}
+
+func newAction(client *github.Client, cfg icassigner.Config, logger log.Logger) (*icassigner.Action, error) {
+	prAssignment := githubaction.GetInputOrDefault("pr-assignment", icassigner.PullRequestAssignReviewer)
+	if prAssignment != icassigner.PullRequestAssignReviewer && prAssignment != icassigner.PullRequestAssignAssignee {
+		return nil, fmt.Errorf("invalid pr-assignment %q, must be %q or %q", prAssignment, icassigner.PullRequestAssignReviewer, icassigner.PullRequestAssignAssignee)
+	}
+
+	action := icassigner.NewAction(client, cfg, logger)
+	action.PullRequestAssignment = prAssignment
+	return action, nil
+}
+
+func loadConfig(ctx context.Context, logger log.Logger) (*github.Client, icassigner.Config, error) {
+	owner, repo, sha, err := githubaction.Repository()
+	if err != nil {
+		return nil, icassigner.Config{}, fmt.Errorf("unable to identify current github repo, due %w", err)
+	}
+
+	cfgPath := githubaction.GetInputOrDefault("cfg-path", defaultCfgPath)
+
+	client, err := githubaction.NewGithubClientFromEnv()
+	if err != nil {
+		return nil, icassigner.Config{}, fmt.Errorf("unable to create github client, due %w", err)
+	}
+
+	cfgReader, err := icassigner.FetchConfig(ctx, client, owner, repo, sha, cfgPath)
+	if err != nil {
+		return nil, icassigner.Config{}, fmt.Errorf("unable to get config, due %w", err)
+	}
+
+	cfg, err := icassigner.ParseConfig(cfgReader)
+	if err != nil {
+		return nil, icassigner.Config{}, fmt.Errorf("unable to parse config, due %w", err)
+	}
+
+	err = cfg.ResolveGithubTeams(ctx, client)
+	if err != nil {
+		return nil, icassigner.Config{}, fmt.Errorf("unable to resolve github teams, due %w", err)
+	}
+
+	err = cfg.ResolveCodeOwners(ctx, logger, client, owner, repo, sha)
+	if err != nil {
+		return nil, icassigner.Config{}, fmt.Errorf("unable to resolve code owners, due %w", err)
+	}
+
+	// members added from github teams and code owners can get their calendar from ical-urls as well
+	err = cfg.ResolveCalendarSecrets(githubaction.GetInputOrDefault("ical-urls", ""))
+	if err != nil {
+		return nil, icassigner.Config{}, fmt.Errorf("unable to resolve calendar secrets, due %w", err)
+	}
+
+	return client, cfg, nil
 }
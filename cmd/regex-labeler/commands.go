@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labeler"
+	"gopkg.in/yaml.v2"
+)
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "eval":
+		return runEval(args, os.Stdout)
+	case "backtest":
+		return runBacktest(args, os.Stdout)
+	case "suggest":
+		return runSuggest(args, os.Stdout)
+	case "validate":
+		return runValidate(args, os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q, available commands: eval, backtest, suggest, validate", name)
+	}
+}
+
+// runEval evaluates a local config against a single issue and prints the score of every label.
+// This allows to iterate on regular expressions without opening test issues.
+func runEval(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	cfgPath := fs.String("config", ".github/regex-labeler.yml", "Path to the regex labeler config file")
+	title := fs.String("title", "", "Title of the issue to evaluate")
+	body := fs.String("body", "", "Body of the issue to evaluate")
+	issuePath := fs.String("issue", "", "Path to a JSON file containing a github issue, as returned by the github API. Overrides -title and -body")
+	files := fs.String("files", "", "Comma separated list of changed files, to evaluate path matchers as for a pull request")
+	verbose := fs.Bool("v", false, "Log the evaluation of every matcher")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadLocalConfig(*cfgPath)
+	if err != nil {
+		return err
+	}
+
+	iss := issue.Issue{Title: *title, Body: *body}
+	if *issuePath != "" {
+		iss, err = loadIssue(*issuePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *files != "" {
+		iss.IsPullRequest = true
+		iss.ChangedFiles = strings.Split(*files, ",")
+	}
+
+	if iss.Title == "" && iss.Body == "" && len(iss.ChangedFiles) == 0 {
+		return errors.New("either -issue, -files or at least one of -title and -body is required")
+	}
+
+	logger := log.NewNopLogger()
+	if *verbose {
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	}
+
+	l := labeler.NewLabeler(cfg, nil, true, logger)
+	label, scores, err := l.FindLabel(iss)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LABEL\tSCORE\tMATCHED")
+	for _, s := range scores {
+		fmt.Fprintf(w, "%s\t%.4g\t%s\n", s.Label, s.Score, strings.Join(s.Matched, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if err != nil {
+		fmt.Fprintf(out, "\nNo label would be assigned: %v\n", err)
+		return nil
+	}
+
+	fmt.Fprintf(out, "\nLabel %q would be assigned\n", label)
+	return nil
+}
+
+// runBacktest evaluates a local config against a JSONL export of historical issues and reports how well
+// the predicted labels match the labels those issues ended up with.
+func runBacktest(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	cfgPath := fs.String("config", ".github/regex-labeler.yml", "Path to the regex labeler config file")
+	issuesPath := fs.String("issues", "", "Path to a JSONL file containing one github issue per line, as returned by the github API")
+	verbose := fs.Bool("v", false, "Log the evaluation of every matcher")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *issuesPath == "" {
+		return errors.New("-issues is required")
+	}
+
+	cfg, err := loadLocalConfig(*cfgPath)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*issuesPath)
+	if err != nil {
+		return fmt.Errorf("unable to open issues file, due %w", err)
+	}
+	defer f.Close()
+
+	examples, err := labeler.ReadExamples(f)
+	if err != nil {
+		return err
+	}
+
+	logger := log.NewNopLogger()
+	if *verbose {
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	}
+
+	report := labeler.NewLabeler(cfg, nil, true, logger).Backtest(examples)
+
+	fmt.Fprintf(out, "Accuracy: %.1f%% (%d/%d)\n\n", 100*report.Accuracy(), report.Correct, report.Total)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LABEL\tTP\tFP\tFN\tPRECISION\tRECALL")
+	for _, m := range report.Labels {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f\t%.2f\n", m.Label, m.TruePositives, m.FalsePositives, m.FalseNegatives, m.Precision(), m.Recall())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nConfusion matrix (rows: actual label, columns: predicted label)")
+	labels := report.ConfusionLabels()
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "\t%s\n", strings.Join(labels, "\t"))
+	for _, actual := range labels {
+		row := make([]string, len(labels))
+		for i, predicted := range labels {
+			row[i] = fmt.Sprint(report.Confusion[actual][predicted])
+		}
+		fmt.Fprintf(w, "%s\t%s\n", actual, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// runSuggest proposes matchers for labels based on a JSONL export of historical issues and prints them as config snippet.
+func runSuggest(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	issuesPath := fs.String("issues", "", "Path to a JSONL file containing one github issue per line, as returned by the github API")
+	labels := fs.String("labels", "", "Comma separated list of labels to suggest matchers for. Defaults to all labels found in the issues")
+	maxMatchers := fs.Int("max-matchers", 5, "Maximum amount of matchers suggested per label")
+	minSupport := fs.Int("min-support", 2, "Minimum amount of issues with a label a word needs to appear in to be suggested")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *issuesPath == "" {
+		return errors.New("-issues is required")
+	}
+
+	f, err := os.Open(*issuesPath)
+	if err != nil {
+		return fmt.Errorf("unable to open issues file, due %w", err)
+	}
+	defer f.Close()
+
+	examples, err := labeler.ReadExamples(f)
+	if err != nil {
+		return err
+	}
+
+	opts := labeler.SuggestOptions{
+		MaxMatchers: *maxMatchers,
+		MinSupport:  *minSupport,
+	}
+	if *labels != "" {
+		opts.Labels = strings.Split(*labels, ",")
+	}
+
+	raw, err := yaml.Marshal(labeler.SuggestMatchers(examples, opts))
+	if err != nil {
+		return fmt.Errorf("unable to render suggestions, due %w", err)
+	}
+
+	_, err = out.Write(raw)
+	return err
+}
+
+// runValidate checks a local config strictly and reports findings of the lint rules, e.g. labels which are never assigned.
+// It fails if the config is invalid or anything was found, so it can be used in CI.
+func runValidate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	cfgPath := fs.String("config", ".github/regex-labeler.yml", "Path to the regex labeler config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	raw, err := os.ReadFile(*cfgPath)
+	if err != nil {
+		return fmt.Errorf("unable to read config, due %w", err)
+	}
+
+	cfg, err := labeler.ParseConfig(raw)
+	if err != nil {
+		return fmt.Errorf("unable to parse config, due %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config, due %w", err)
+	}
+
+	findings := cfg.Lint()
+	for _, f := range findings {
+		fmt.Fprintln(out, f)
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d problems in %s", len(findings), *cfgPath)
+	}
+
+	fmt.Fprintf(out, "%s is valid\n", *cfgPath)
+	return nil
+}
+
+func loadLocalConfig(path string) (labeler.Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return labeler.Config{}, fmt.Errorf("unable to read config, due %w", err)
+	}
+
+	cfg, err := labeler.ParseConfig(raw)
+	if err != nil {
+		return cfg, fmt.Errorf("unable to parse config, due %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config, due %w", err)
+	}
+
+	if cfg.Engine == labeler.EngineBayes {
+		// the corpus path is relative to the repository root, which is expected to be the working directory
+		f, err := os.Open(cfg.Bayes.Corpus)
+		if err != nil {
+			return cfg, fmt.Errorf("unable to open bayes corpus, due %w", err)
+		}
+		defer f.Close()
+
+		corpus, err := labeler.ReadExamples(f)
+		if err != nil {
+			return cfg, err
+		}
+
+		if err := cfg.Train(corpus); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadIssue(path string) (issue.Issue, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return issue.Issue{}, fmt.Errorf("unable to read issue file, due %w", err)
+	}
+
+	return issue.Parse(raw)
+}
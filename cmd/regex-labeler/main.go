@@ -17,8 +17,10 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/go-kit/log"
@@ -26,25 +28,63 @@ import (
 
 	"github.com/google/go-github/github"
 	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
 	"github.com/grafana/escalation-scheduler/pkg/labeler"
+	"github.com/grafana/escalation-scheduler/pkg/logging"
+	"github.com/grafana/escalation-scheduler/pkg/redact"
 )
 
 func main() {
-	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	// Without any arguments we run as github action, otherwise the first argument selects a local subcommand.
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger, err := logging.New(os.Stderr, githubaction.GetInputOrDefault("log-format", logging.FormatLogfmt), githubaction.GetInputOrDefault("log-level", logging.LevelInfo))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
+	redactor, err := redact.New(githubaction.GetInputOrDefault("log-redaction", redact.ModeSecrets))
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid input", "err", err)
+		os.Exit(1)
+	}
+	redact.SetDefault(redactor)
+
+	runAction(redactor.Logger(logger))
+}
+
+func runAction(logger log.Logger) {
 	actionCtx, err := githubaction.LoadContext()
 	if err != nil {
 		level.Error(logger).Log("msg", "unable to load github context", "err", err)
 		return
 	}
 
-	if actionCtx.Issue == nil {
-		level.Error(logger).Log("msg", "can not be used without an issue")
+	if actionCtx.Issue.Number == 0 {
+		level.Error(logger).Log("msg", "can not be used without an issue or pull request")
 		return
 	}
 
-	if actionCtx.Issue.GetState() != "open" {
-		level.Error(logger).Log("msg", "only works on currently open issues", "currentState", actionCtx.Issue.GetState())
+	// comments only trigger a run if they ask for a retriage
+	retriage := false
+	if actionCtx.EventName == githubaction.EventIssueComment {
+		command, _, ok := actionCtx.Command()
+		if actionCtx.Action != "created" || !ok || command != githubaction.CommandRetriage {
+			level.Info(logger).Log("msg", "comment doesn't contain a supported command, stopping")
+			return
+		}
+		retriage = true
+	}
+
+	if actionCtx.Issue.State != "open" {
+		level.Error(logger).Log("msg", "only works on currently open issues", "currentState", actionCtx.Issue.State)
 		return
 	}
 
@@ -80,39 +120,43 @@ func main() {
 		return
 	}
 
-	eventName := os.Getenv("GITHUB_EVENT_NAME")
-	if eventName == "" {
-		level.Error(logger).Log("msg", "missing env var GITHUB_EVENT_NAME")
-		return
-	}
+	if cfg.Engine == labeler.EngineBayes {
+		corpusContent, err := downloadFile(gh, owner, repo, sha, cfg.Bayes.Corpus)
+		if err != nil {
+			level.Error(logger).Log("msg", "unable to get bayes corpus", "err", err)
+			return
+		}
 
-	eventFile := os.Getenv("GITHUB_EVENT_PATH")
-	if eventFile == "" {
-		level.Error(logger).Log("msg", "missing env var GITHUB_EVENT_PATH")
-		return
-	}
+		corpus, err := labeler.ReadExamples(bytes.NewReader(corpusContent))
+		if err != nil {
+			level.Error(logger).Log("msg", "error when parsing bayes corpus", "err", err)
+			return
+		}
 
-	rawContext, err := os.ReadFile(eventFile)
-	if err != nil {
-		level.Error(logger).Log("msg", "error when reading event file", "err", err)
-		return
+		err = cfg.Train(corpus)
+		if err != nil {
+			level.Error(logger).Log("msg", "error when training bayes model", "err", err)
+			return
+		}
 	}
 
-	githubCtx, err := github.ParseWebHook(eventName, rawContext)
-	if err != nil {
-		level.Error(logger).Log("msg", "error parsing github web hook", "err", err)
-		return
+	iss := actionCtx.Issue
+	if iss.IsPullRequest && cfg.UsesPaths() {
+		iss.ChangedFiles, err = issue.ListChangedFiles(context.Background(), gh, iss.Owner, iss.Repo, iss.Number)
+		if err != nil {
+			level.Error(logger).Log("msg", "unable to get changed files of pull request", "err", err)
+			return
+		}
 	}
 
-	issuesEvent, ok := githubCtx.(*github.IssuesEvent)
-	if !ok {
-		level.Error(logger).Log("msg", "event is not an issue event")
-		return
-	}
+	dryRun := githubaction.GetInputOrDefault("dry-run", "false") != "false"
 
-	l := labeler.NewLabeler(cfg, gh, logger)
-	issue := issuesEvent.GetIssue()
-	err = l.Run(issue)
+	l := labeler.NewLabeler(cfg, gh, dryRun, logger)
+	if retriage {
+		err = l.Retriage(iss)
+	} else {
+		err = l.Run(iss)
+	}
 	if err != nil {
 		level.Error(logger).Log("msg", "failed to assign label", "err", err)
 		return
@@ -135,3 +179,22 @@ func fetchConfig(client *github.Client, owner, repo, ref, path string) ([]byte,
 
 	return []byte(content), nil
 }
+
+// downloadFile returns the content of a file of the repository. Unlike fetchConfig it isn't limited to files of up to 1 MB,
+// which a bayes corpus can easily exceed.
+func downloadFile(client *github.Client, owner, repo, ref, path string) ([]byte, error) {
+	rc, err := client.Repositories.DownloadContents(context.Background(), owner, repo, path, &github.RepositoryContentGetOptions{
+		Ref: ref,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s, due %w", path, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s, due %w", path, err)
+	}
+
+	return content, nil
+}
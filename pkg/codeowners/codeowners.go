@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codeowners parses CODEOWNERS files as supported by GitHub.
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/grafana/escalation-scheduler/pkg/glob"
+)
+
+// Rule is a single line of a CODEOWNERS file.
+type Rule struct {
+	Pattern string
+
+	// Owners are users ("@someone"), teams ("@org/team") or email addresses. Rules without owners unset the owners of earlier rules.
+	Owners []string
+}
+
+// File is a parsed CODEOWNERS file.
+type File []Rule
+
+// Parse parses a CODEOWNERS file, ignoring comments and empty lines.
+func Parse(r io.Reader) (File, error) {
+	var result File
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// strip trailing comments
+		if idx := strings.Index(line, " #"); idx >= 0 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(line)
+		if err := glob.Validate(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in line %d: %w", fields[0], lineNumber, err)
+		}
+
+		result = append(result, Rule{Pattern: fields[0], Owners: fields[1:]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read CODEOWNERS, due %w", err)
+	}
+
+	return result, nil
+}
+
+// Owners returns the owners of a file path. Like on GitHub, the last matching rule wins.
+// A rule matching a directory owns everything below it.
+func (f File) Owners(name string) []string {
+	name = strings.TrimPrefix(name, "/")
+
+	for i := len(f) - 1; i >= 0; i-- {
+		if matches(f[i].Pattern, name) {
+			return f[i].Owners
+		}
+	}
+	return nil
+}
+
+func matches(pattern, name string) bool {
+	for p := name; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if glob.Match(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
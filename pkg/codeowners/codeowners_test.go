@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codeowners
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwners(t *testing.T) {
+	f, err := Parse(strings.NewReader(`
+# default owners
+*                       @grafana/maintainers
+
+/pkg/ingester/          @grafana/loki-ingest @alice # ingest path
+/pkg/querier            @bob
+*.md                    @grafana/docs
+/pkg/querier/vendored/
+`))
+	require.NoError(t, err)
+	require.Len(t, f, 5)
+
+	require.Equal(t, []string{"@grafana/maintainers"}, f.Owners("go.mod"))
+	require.Equal(t, []string{"@grafana/loki-ingest", "@alice"}, f.Owners("pkg/ingester/ingester.go"))
+	require.Equal(t, []string{"@grafana/loki-ingest", "@alice"}, f.Owners("pkg/ingester"))
+	require.Equal(t, []string{"@bob"}, f.Owners("pkg/querier/querier.go"))
+	require.Equal(t, []string{"@grafana/docs"}, f.Owners("pkg/ingester/README.md"))
+	require.Empty(t, f.Owners("pkg/querier/vendored/lib.go"))
+}
+
+func TestParse_InvalidPattern(t *testing.T) {
+	_, err := Parse(strings.NewReader("pkg/[a- @alice"))
+	require.Error(t, err)
+}
@@ -17,43 +17,143 @@
 package githubaction
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
 )
 
-func LoadContext() (*github.IssuesEvent, error) {
-	var ctx *github.IssuesEvent
+const (
+	EventIssues            = "issues"
+	EventIssueComment      = "issue_comment"
+	EventPullRequest       = "pull_request"
+	EventPullRequestTarget = "pull_request_target"
+)
+
+// CommandRetriage is the comment command, i.e. "/retriage", which asks the actions to run again on an issue.
+const CommandRetriage = "retriage"
+
+// Context contains the event which triggered the current workflow run, normalized for all supported events.
+type Context struct {
+	// EventName is the name of the event, e.g. "issues" or "pull_request".
+	EventName string
+
+	// Action is the activity type of the event, e.g. "opened" or "created".
+	Action string
+
+	// Sender is the login of the user who triggered the event.
+	Sender string
+
+	// Issue is the issue or pull request of the event in the common issue model.
+	Issue issue.Issue
+
+	// Comment is only set for issue_comment events.
+	Comment *github.IssueComment
+}
 
+// Command returns the slash command of the comment which triggered the event, e.g. "retriage" for a comment "/retriage".
+// Only the first line of a comment is considered. ok is false if the event isn't a comment or the comment isn't a command.
+func (c *Context) Command() (name string, args []string, ok bool) {
+	if c.Comment == nil {
+		return "", nil, false
+	}
+
+	return ParseCommand(c.Comment.GetBody())
+}
+
+// ParseCommand parses a slash command from the first line of a comment body.
+func ParseCommand(body string) (name string, args []string, ok bool) {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	fields := strings.Fields(firstLine)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") || len(fields[0]) == 1 {
+		return "", nil, false
+	}
+
+	return strings.ToLower(strings.TrimPrefix(fields[0], "/")), fields[1:], true
+}
+
+// LoadContext loads the event of the current workflow run. Supported events are issues, issue_comment,
+// pull_request and pull_request_target.
+func LoadContext() (*Context, error) {
 	eventName := os.Getenv("GITHUB_EVENT_NAME")
 	if eventName == "" {
-		return ctx, errors.New("missing env var GITHUB_EVENT_NAME")
+		return nil, errors.New("missing env var GITHUB_EVENT_NAME")
 	}
 
 	eventFile := os.Getenv("GITHUB_EVENT_PATH")
 	if eventFile == "" {
-		return ctx, errors.New("missing env var GITHUB_EVENT_PATH")
+		return nil, errors.New("missing env var GITHUB_EVENT_PATH")
 	}
 
 	rawContext, err := os.ReadFile(eventFile)
 	if err != nil {
-		return ctx, fmt.Errorf("error when reading event file: %w", err)
+		return nil, fmt.Errorf("error when reading event file: %w", err)
+	}
+
+	return ParseContext(eventName, rawContext)
+}
+
+// ParseContext parses the payload of an event with the given name.
+func ParseContext(eventName string, rawContext []byte) (*Context, error) {
+	// pull_request_target events carry the same payload as pull_request events, but are unknown to go-github
+	webhookName := eventName
+	if eventName == EventPullRequestTarget {
+		webhookName = EventPullRequest
+	}
+
+	githubCtx, err := github.ParseWebHook(webhookName, rawContext)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing github event: %w", err)
+	}
+
+	// issues and pull requests are parsed separately as go-github doesn't expose all fields of the payload
+	var payload struct {
+		Issue       json.RawMessage `json:"issue"`
+		PullRequest json.RawMessage `json:"pull_request"`
+	}
+	if err := json.Unmarshal(rawContext, &payload); err != nil {
+		return nil, fmt.Errorf("error parsing github event: %w", err)
+	}
+
+	ctx := &Context{EventName: eventName}
+
+	var repo *github.Repository
+	switch event := githubCtx.(type) {
+	case *github.IssuesEvent:
+		ctx.Action = event.GetAction()
+		ctx.Sender = event.GetSender().GetLogin()
+		repo = event.Repo
+		ctx.Issue, err = issue.Parse(payload.Issue)
+	case *github.IssueCommentEvent:
+		ctx.Action = event.GetAction()
+		ctx.Sender = event.GetSender().GetLogin()
+		ctx.Comment = event.Comment
+		repo = event.Repo
+		ctx.Issue, err = issue.Parse(payload.Issue)
+	case *github.PullRequestEvent:
+		ctx.Action = event.GetAction()
+		ctx.Sender = event.GetSender().GetLogin()
+		repo = event.Repo
+		ctx.Issue, err = issue.ParsePullRequest(payload.PullRequest)
+	default:
+		return nil, fmt.Errorf("unsupported event %q, supported are %s, %s, %s and %s", eventName, EventIssues, EventIssueComment, EventPullRequest, EventPullRequestTarget)
 	}
 
-	githubCtx, err := github.ParseWebHook(eventName, rawContext)
 	if err != nil {
-		return ctx, fmt.Errorf("error parsing github event: %w", err)
+		return nil, err
 	}
 
-	ctx, ok := githubCtx.(*github.IssuesEvent)
-	if !ok {
-		return ctx, errors.New("event is not an issue event")
+	// the repository of the event takes precedence over the one derived from the issue url
+	if repo.GetOwner().GetLogin() != "" && repo.GetName() != "" {
+		ctx.Issue.Owner = repo.GetOwner().GetLogin()
+		ctx.Issue.Repo = repo.GetName()
 	}
 
-	return ctx, err
+	return ctx, nil
 }
 
 func Repository() (string, string, string, error) {
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		expectedName string
+		expectedArgs []string
+		expectedOk   bool
+	}{
+		{name: "command", body: "/retriage", expectedName: "retriage", expectedArgs: []string{}, expectedOk: true},
+		{name: "case insensitive", body: "/ReTriage", expectedName: "retriage", expectedArgs: []string{}, expectedOk: true},
+		{name: "arguments keep their case", body: "/assign-team Loki", expectedName: "assign-team", expectedArgs: []string{"Loki"}, expectedOk: true},
+		{name: "surrounding whitespace", body: "  \n/assign-team   loki  ", expectedName: "assign-team", expectedArgs: []string{"loki"}, expectedOk: true},
+		{name: "only the first line", body: "/handover @bob\n/retriage", expectedName: "handover", expectedArgs: []string{"@bob"}, expectedOk: true},
+		{name: "quotes don't group arguments", body: `/assign-team "loki squad"`, expectedName: "assign-team", expectedArgs: []string{`"loki`, `squad"`}, expectedOk: true},
+		// unknown commands are parsed as well, it's up to the actions which commands they support
+		{name: "unknown command", body: "/deploy production", expectedName: "deploy", expectedArgs: []string{"production"}, expectedOk: true},
+		{name: "quoted reply", body: "> /retriage\n\nwhy?"},
+		{name: "command not at the start", body: "please /retriage"},
+		{name: "command on a later line", body: "thanks!\n/retriage"},
+		{name: "slash only", body: "/ retriage"},
+		{name: "empty", body: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, args, ok := ParseCommand(tc.body)
+			require.Equal(t, tc.expectedOk, ok)
+			require.Equal(t, tc.expectedName, name)
+			require.Equal(t, tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	testCases := []struct {
+		name            string
+		eventName       string
+		payload         string
+		expectedAction  string
+		expectedSender  string
+		expectedNumber  int
+		expectedPR      bool
+		expectedCommand string
+	}{
+		{
+			name:           "issues",
+			eventName:      EventIssues,
+			payload:        `{"action": "opened", "sender": {"login": "alice"}, "repository": {"name": "repo", "owner": {"login": "owner"}}, "issue": {"number": 1, "repository_url": "https://api.github.com/repos/owner/repo"}}`,
+			expectedAction: "opened",
+			expectedSender: "alice",
+			expectedNumber: 1,
+		},
+		{
+			name:            "issue comment",
+			eventName:       EventIssueComment,
+			payload:         `{"action": "created", "sender": {"login": "bob"}, "repository": {"name": "repo", "owner": {"login": "owner"}}, "issue": {"number": 2}, "comment": {"id": 3, "body": "/Retriage"}}`,
+			expectedAction:  "created",
+			expectedSender:  "bob",
+			expectedNumber:  2,
+			expectedCommand: "retriage",
+		},
+		{
+			name:           "pull request target",
+			eventName:      EventPullRequestTarget,
+			payload:        `{"action": "opened", "sender": {"login": "carol"}, "repository": {"name": "repo", "owner": {"login": "owner"}}, "pull_request": {"number": 4, "head": {"sha": "abc"}}}`,
+			expectedAction: "opened",
+			expectedSender: "carol",
+			expectedNumber: 4,
+			expectedPR:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := ParseContext(tc.eventName, []byte(tc.payload))
+			require.NoError(t, err)
+
+			require.Equal(t, tc.eventName, ctx.EventName)
+			require.Equal(t, tc.expectedAction, ctx.Action)
+			require.Equal(t, tc.expectedSender, ctx.Sender)
+			require.Equal(t, tc.expectedNumber, ctx.Issue.Number)
+			require.Equal(t, tc.expectedPR, ctx.Issue.IsPullRequest)
+			require.Equal(t, "owner", ctx.Issue.Owner)
+			require.Equal(t, "repo", ctx.Issue.Repo)
+
+			command, _, ok := ctx.Command()
+			require.Equal(t, tc.expectedCommand != "", ok)
+			require.Equal(t, tc.expectedCommand, command)
+		})
+	}
+}
+
+func TestParseContext_UnsupportedEvent(t *testing.T) {
+	_, err := ParseContext("push", []byte(`{"ref": "refs/heads/main"}`))
+	require.ErrorContains(t, err, `unsupported event "push"`)
+
+	_, err = ParseContext(EventIssues, []byte(`not json`))
+	require.Error(t, err)
+}
@@ -17,27 +17,73 @@
 package githubaction
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 )
 
+// SetOutput sets the output of the action to value, which can span multiple lines.
 func SetOutput(output, value string) error {
+	return SetOutputs(map[string]string{output: value})
+}
+
+// SetOutputs sets multiple outputs of the action at once, in order of their names.
+// Values are written with a random delimiter, so that they can span multiple lines or contain JSON.
+func SetOutputs(outputs map[string]string) error {
 	outputFile := os.Getenv("GITHUB_OUTPUT")
 	if outputFile == "" {
 		return errors.New("only output with a github output file supported. See https://github.blog/changelog/2022-10-11-github-actions-deprecating-save-state-and-set-output-commands/ for further details")
 	}
 
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		delimiter, err := outputDelimiter()
+		if err != nil {
+			return err
+		}
+
+		value := outputs[name]
+		if strings.Contains(name, delimiter) || strings.Contains(value, delimiter) {
+			return fmt.Errorf("unable to set output %q, as it contains the delimiter", name)
+		}
+
+		fmt.Fprintf(&sb, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+	}
+
 	f, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("unable to open output file, due %w", err)
 	}
 	defer f.Close()
 
-	_, err = fmt.Fprintf(f, "%v=%v\n", output, value)
+	_, err = f.WriteString(sb.String())
 	if err != nil {
 		return fmt.Errorf("unable to write to output file, due %w", err)
 	}
 
 	return nil
 }
+
+// outputDelimiter returns a random delimiter for multiline outputs, which can't be guessed by whoever controls the value.
+func outputDelimiter() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate output delimiter, due %w", err)
+	}
+	return "ghadelimiter_" + hex.EncodeToString(b), nil
+}
+
+// AddMask masks the value in the log of the workflow run, e.g. secrets which are not passed as secret directly.
+func AddMask(value string) {
+	fmt.Fprintf(os.Stdout, "::add-mask::%s\n", value)
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubaction
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOutputs(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+
+	require.NoError(t, SetOutputs(map[string]string{
+		"scores": `{"label": "bug", "score": 0.8}`,
+		"body":   "first line\nsecond line",
+	}))
+	require.NoError(t, SetOutput("label", "bug"))
+
+	content, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+
+	// outputs are sorted by name and every value is enclosed by its own random delimiter
+	re := regexp.MustCompile(`(?s)^body<<(ghadelimiter_[0-9a-f]{32})\nfirst line\nsecond line\n(ghadelimiter_[0-9a-f]{32})\n` +
+		`scores<<(ghadelimiter_[0-9a-f]{32})\n\{"label": "bug", "score": 0.8\}\n(ghadelimiter_[0-9a-f]{32})\n` +
+		`label<<(ghadelimiter_[0-9a-f]{32})\nbug\n(ghadelimiter_[0-9a-f]{32})\n$`)
+	m := re.FindStringSubmatch(string(content))
+	require.NotNil(t, m, "unexpected output file:\n%s", content)
+	require.Equal(t, m[1], m[2])
+	require.Equal(t, m[3], m[4])
+	require.Equal(t, m[5], m[6])
+	require.NotEqual(t, m[1], m[3], "expected a new delimiter per output")
+}
+
+func TestSetOutputs_WithoutOutputFile(t *testing.T) {
+	t.Setenv("GITHUB_OUTPUT", "")
+	require.Error(t, SetOutput("label", "bug"))
+}
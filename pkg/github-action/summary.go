@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubaction
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AppendSummary appends markdown to the job summary of the workflow run, which is shown on its summary page.
+// Nothing is written if the summary file isn't available, e.g. when running outside of GitHub Actions.
+func AppendSummary(markdown string) error {
+	summaryFile := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryFile == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open summary file, due %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, markdown)
+	if err != nil {
+		return fmt.Errorf("unable to write to summary file, due %w", err)
+	}
+
+	return nil
+}
+
+// MarkdownTable renders a Markdown table with the given header and rows.
+// Pipes and line breaks within cells are escaped, so that they don't break the table.
+func MarkdownTable(header []string, rows [][]string) string {
+	var sb strings.Builder
+
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for _, c := range cells {
+			sb.WriteString(" " + escapeCell(c) + " |")
+		}
+		sb.WriteString("\n")
+	}
+
+	writeRow(header)
+	sb.WriteString("|")
+	for range header {
+		sb.WriteString(" --- |")
+	}
+	sb.WriteString("\n")
+
+	for _, r := range rows {
+		writeRow(r)
+	}
+
+	return sb.String()
+}
+
+var cellReplacer = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+func escapeCell(s string) string {
+	return cellReplacer.Replace(s)
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{pattern: "pkg/ingester/*.go", name: "pkg/ingester/ingester.go", match: true},
+		{pattern: "pkg/ingester/*.go", name: "pkg/ingester/client/client.go", match: false},
+		{pattern: "pkg/ingester/**", name: "pkg/ingester/client/client.go", match: true},
+		{pattern: "pkg/ingester/", name: "pkg/ingester/client/client.go", match: true},
+		{pattern: "pkg/**/client.go", name: "pkg/client.go", match: true},
+		{pattern: "pkg/**/client.go", name: "pkg/ingester/client/client.go", match: true},
+		{pattern: "pkg/**/client.go", name: "cmd/client.go", match: false},
+		{pattern: "*.md", name: "docs/sources/index.md", match: true},
+		{pattern: "/README.md", name: "README.md", match: true},
+		{pattern: "/README.md", name: "docs/README.md", match: false},
+		{pattern: "docs/**/*.md", name: "docs/index.md", match: true},
+		{pattern: "docs", name: "docs/index.md", match: false},
+		{pattern: "docs/", name: "website/docs/index.md", match: true},
+		{pattern: "/docs/", name: "website/docs/index.md", match: false},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.match, Match(tc.pattern, tc.name), "pattern %q, path %q", tc.pattern, tc.name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate("pkg/**/*.go"))
+	require.Error(t, Validate("pkg/[a-"))
+}
@@ -20,32 +20,96 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/google/go-github/github"
 	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
 	"github.com/grafana/escalation-scheduler/pkg/icassigner/busyness"
 	"github.com/grafana/escalation-scheduler/pkg/icassigner/calendar"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/redact"
+)
+
+const (
+	// OverCapacityAssign assigns issues even if every member is at capacity.
+	OverCapacityAssign = "assign"
+
+	// OverCapacityEscalate mentions the backup of the team instead of assigning issues if every member is at capacity.
+	OverCapacityEscalate = "escalate"
+
+	// OverCapacityLabel leaves issues unassigned and labels them with the needs owner label if every member is at capacity.
+	OverCapacityLabel = "label"
+
+	// DefaultNeedsOwnerLabel is the label added by OverCapacityLabel if the team doesn't configure one.
+	DefaultNeedsOwnerLabel = "needs-owner"
+)
+
+// errAtCapacity is returned by assign if every member is at capacity and the team doesn't want to assign anyway.
+var errAtCapacity = errors.New("every member of the team is at capacity")
+
+const (
+	// PullRequestAssignReviewer requests a review of the chosen member on pull requests.
+	PullRequestAssignReviewer = "reviewer"
+
+	// PullRequestAssignAssignee assigns the chosen member to pull requests, the same way as issues.
+	PullRequestAssignAssignee = "assignee"
 )
 
 type Action struct {
 	Client *github.Client
 	Config Config
+
+	// PullRequestAssignment defines how the chosen member is assigned to pull requests, defaults to PullRequestAssignReviewer.
+	PullRequestAssignment string
+
+	// Logger receives the log output of the action, nothing is logged if it is nil.
+	Logger log.Logger
+
+	// Login is the GitHub login the action acts as, only assignments done by it are escalated and rebalanced.
+	// If empty, it's determined from the token, falling back to DefaultLogin for installation tokens.
+	Login string
+
+	// availabilityCache caches the availability of members by lowercase name while set, see availability.
+	availabilityCache map[string]availabilityResult
+
+	// assignments collects the assignments while set, so that runs handling multiple issues set their outputs once.
+	assignments []Assignment
+}
+
+type availabilityResult struct {
+	available bool
+	err       error
+}
+
+// NewAction creates a new Action based on the given config, which logs to logger.
+func NewAction(client *github.Client, cfg Config, logger log.Logger) *Action {
+	return &Action{Client: client, Config: cfg, Logger: logger}
+}
+
+// logger returns the logger of the action, falling back to a logger discarding everything.
+func (a *Action) logger() log.Logger {
+	if a.Logger == nil {
+		return log.NewNopLogger()
+	}
+	return a.Logger
 }
 
-func (a *Action) Run(ctx context.Context, event *github.IssuesEvent, labelsInput string, dryRun bool) error {
-	labels := convertLabels(event.Issue.Labels)
+func (a *Action) Run(ctx context.Context, iss issue.Issue, labelsInput string, dryRun bool) error {
 	if labelsInput != "" {
-		labels = strings.Split(labelsInput, ",")
+		iss.Labels = strings.Split(labelsInput, ",")
 	}
 
 	for _, i := range a.Config.IgnoredLabels {
-		for _, l := range labels {
+		for _, l := range iss.Labels {
 			if i == l {
-				log.Printf("Label %q which marks an issue as to be ignored found. Stopping\n", i)
+				level.Info(a.logger()).Log("msg", "label marks the issue as to be ignored, stopping", "label", i)
+				a.report(Assignment{Issue: iss.Number, Skipped: fmt.Sprintf("the label %q marks the issue as to be ignored", i)})
 				return nil
 			}
 		}
@@ -53,33 +117,206 @@ func (a *Action) Run(ctx context.Context, event *github.IssuesEvent, labelsInput
 
 	// finds the team this issue is assigned to
 	// TODO: Decide if we want to change this behavior if multiple teams match. We could create an adhoc bigger team and then simply distribute the escalations there
-	teamMembers, teamName := findTeam(a.Config, labels)
-	if len(teamMembers) == 0 {
-		log.Print("No team is responsible for this issue. Stopping\n")
+	team, teamName := findTeam(a.Config, iss, time.Now())
+	if len(team.allMembers()) == 0 {
+		level.Info(a.logger()).Log("msg", "no team is responsible for this issue, stopping")
+		a.report(Assignment{Issue: iss.Number, Skipped: "no team is responsible for the issue"})
 		return nil // no team is responsible for anything, so we just abort
 	}
 
 	// check if someone from the team is already assigned (skip in this case)
-	if assigned, teamMember := isTeamMemberAssigned(teamMembers, event.Issue.Assignees); assigned {
-		log.Printf("Found assignee %q which is member of the matched team %q. Stopping\n", teamMember, teamName)
+	if assigned, teamMember := isTeamMemberAssigned(team.allMembers(), currentOwners(iss)); assigned {
+		level.Info(a.logger()).Log("msg", "found assignee which is member of the matched team, stopping", "assignee", teamMember, "team", teamName)
+		a.report(Assignment{Issue: iss.Number, Team: teamName, Skipped: fmt.Sprintf("%s of team %q is already assigned", teamMember, teamName)})
+		return nil
+	}
+
+	_, err := a.assign(ctx, iss, team, teamName, nil, nil, dryRun)
+	if errors.Is(err, errAtCapacity) {
+		return a.handleOverCapacity(ctx, iss, team, teamName, dryRun)
+	}
+	return err
+}
+
+// handleOverCapacity applies the overCapacity policy of the team to an issue nobody can take.
+func (a *Action) handleOverCapacity(ctx context.Context, iss issue.Issue, team TeamConfig, teamName string, dryRun bool) error {
+	level.Info(a.logger()).Log("msg", "every member of the team is at capacity, applying policy", "team", teamName, "policy", team.OverCapacity)
+
+	if dryRun {
+		level.Info(a.logger()).Log("msg", "exiting because dry-run is enabled")
 		return nil
 	}
 
+	switch team.OverCapacity {
+	case OverCapacityEscalate:
+		return a.comment(ctx, iss, fmt.Sprintf("@%s every member of team %q is at capacity, please find an owner for this issue.", strings.TrimPrefix(team.Backup, "@"), teamName))
+	case OverCapacityLabel:
+		label := team.NeedsOwnerLabel
+		if label == "" {
+			label = DefaultNeedsOwnerLabel
+		}
+
+		_, _, err := a.Client.Issues.AddLabelsToIssue(ctx, iss.Owner, iss.Repo, iss.Number, []string{label})
+		if err != nil {
+			return fmt.Errorf("unable to add label %q, due %w", label, err)
+		}
+		return nil
+	default:
+		return errAtCapacity
+	}
+}
+
+// assign chooses the least busy and available member of the team, excluding the members named in exclude and those at
+// capacity, and assigns the issue to them. The tiers of the team are tried in order, if nobody of any tier is available
+// a member of the first tier with capacity left is chosen. If every member is at capacity, errAtCapacity is returned
+// unless the overCapacity policy of the team is to assign anyway. The users in replace are unassigned afterwards,
+// except for the chosen member.
+// It returns the name of the chosen member, which is empty if nobody is left to choose from.
+func (a *Action) assign(ctx context.Context, iss issue.Issue, team TeamConfig, teamName string, exclude, replace []string, dryRun bool) (string, error) {
+	// authors can't review their own pull requests, the exclude list of the caller is left as it is
+	if iss.IsPullRequest {
+		exclude = append(slices.Clone(exclude), iss.Author)
+	}
+
+	var tiers []tier
+	for _, t := range team.tiers() {
+		for _, name := range exclude {
+			t.members = withoutMember(t.members, name)
+		}
+
+		if len(t.members) > 0 {
+			tiers = append(tiers, t)
+		}
+	}
+
+	if len(tiers) == 0 {
+		level.Info(a.logger()).Log("msg", "no member of the team is left to choose from, stopping", "team", teamName)
+		a.report(Assignment{Issue: iss.Number, Team: teamName, Skipped: fmt.Sprintf("no member of team %q is left to choose from", teamName)})
+		return "", nil
+	}
+
+	assignment := Assignment{Issue: iss.Number, Team: teamName, DryRun: dryRun}
+
+	var availableMembers, fallbackMembers []MemberConfig
+	var fallbackTier string
+	for idx, t := range tiers {
+		if idx > 0 {
+			level.Info(a.logger()).Log("msg", "nobody of the tier is available, escalating to the next tier", "tier", tiers[idx-1].name, "next", t.name, "team", teamName)
+		}
+
+		available, withCapacity, candidates, err := a.availableMembers(ctx, team, t)
+		if err != nil {
+			return "", err
+		}
+		assignment.Candidates = append(assignment.Candidates, candidates...)
+
+		if len(fallbackMembers) == 0 {
+			fallbackMembers, fallbackTier = withCapacity, t.name
+		}
+
+		if len(available) > 0 {
+			availableMembers = available
+			break
+		}
+	}
+
+	switch {
+	case len(availableMembers) > 0:
+	case len(fallbackMembers) > 0:
+		// In case no one is available we just consider everybody with capacity left to be available
+		level.Info(a.logger()).Log("msg", "nobody seems to be available, hence we consider everybody of the tier with capacity left to be available", "tier", fallbackTier)
+		assignment.Fallback = fmt.Sprintf("Nobody seems to be available, hence everybody of tier %q with capacity left has been considered available.", fallbackTier)
+		availableMembers = fallbackMembers
+	case team.OverCapacity == "" || team.OverCapacity == OverCapacityAssign:
+		level.Info(a.logger()).Log("msg", "every member is at capacity, hence we consider everybody of the tier to be available", "tier", tiers[0].name)
+		assignment.Fallback = fmt.Sprintf("Every member is at capacity, hence everybody of tier %q has been considered available.", tiers[0].name)
+		availableMembers = tiers[0].members
+	default:
+		assignment.Fallback = fmt.Sprintf("Every member is at capacity, the policy %q of the team is applied.", team.OverCapacity)
+		a.report(assignment)
+		return "", errAtCapacity
+	}
+
+	// Log the available team members.
+	level.Info(a.logger()).Log("msg", "available team members", "members", strings.Join(memberNames(availableMembers), ", "))
+
+	// choose a member
+	theChosenOne := availableMembers[rand.Intn(len(availableMembers))]
+	level.Info(a.logger()).Log("msg", "chose member", "member", theChosenOne.Name)
+
+	assignment.Chosen, assignment.Output = theChosenOne.Name, theChosenOne.Output
+	a.report(assignment)
+
+	if dryRun {
+		level.Info(a.logger()).Log("msg", "exiting because dry-run is enabled")
+		return theChosenOne.Name, nil
+	}
+
+	// the chosen member might already be responsible for the issue, e.g. if it's handed over to a team they are part of
+	replace = slices.DeleteFunc(slices.Clone(replace), func(name string) bool {
+		return strings.EqualFold(name, theChosenOne.Name)
+	})
+
+	if iss.Owner == "" || iss.Repo == "" {
+		return "", errors.New("can't set any assignee as the repository owner or name is missing")
+	}
+
+	if iss.IsPullRequest && a.PullRequestAssignment != PullRequestAssignAssignee {
+		_, _, err := a.Client.PullRequests.RequestReviewers(ctx, iss.Owner, iss.Repo, iss.Number, github.ReviewersRequest{Reviewers: []string{theChosenOne.Name}})
+		if err != nil || len(replace) == 0 {
+			return theChosenOne.Name, err
+		}
+
+		_, err = a.Client.PullRequests.RemoveReviewers(ctx, iss.Owner, iss.Repo, iss.Number, github.ReviewersRequest{Reviewers: replace})
+		return theChosenOne.Name, err
+	}
+
+	_, _, err := a.Client.Issues.AddAssignees(ctx, iss.Owner, iss.Repo, iss.Number, []string{theChosenOne.Name})
+	if err != nil || len(replace) == 0 {
+		return theChosenOne.Name, err
+	}
+
+	// the previous assignees are only removed once someone else is assigned, so that the issue is never left without an assignee
+	_, _, err = a.Client.Issues.RemoveAssignees(ctx, iss.Owner, iss.Repo, iss.Number, replace)
+	return theChosenOne.Name, err
+}
+
+// currentOwners returns everyone who is already responsible for the issue, which includes requested reviewers of pull requests.
+func currentOwners(iss issue.Issue) []string {
+	return append(slices.Clone(iss.Assignees), iss.RequestedReviewers...)
+}
+
+// withoutMember returns the members without the one with the given name.
+func withoutMember(members []MemberConfig, name string) []MemberConfig {
+	result := make([]MemberConfig, 0, len(members))
+	for _, m := range members {
+		if !strings.EqualFold(m.Name, name) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// availableMembers returns the available members of the tier with the lowest busyness and all members with capacity left.
+// Members at capacity are skipped like unavailable members. The candidates explain the availability of every member.
+func (a *Action) availableMembers(ctx context.Context, team TeamConfig, t tier) (available, withCapacity []MemberConfig, candidates []Candidate, err error) {
+	teamMembers := t.members
+
 	// Log the known team member names.
-	log.Printf("Known team members: %q", strings.Join(memberNames(teamMembers), ", "))
+	level.Debug(a.logger()).Log("msg", "known team members", "members", strings.Join(memberNames(teamMembers), ", "))
 
 	// 1. get busyness scores per team member
 	// We calculate busyness first as this is usually cheaper than availability checks
 	busynessPerTeamMember, err := a.calculateIssueBusynessPerTeamMember(ctx, time.Now(), teamMembers)
 	if err != nil {
-		return fmt.Errorf("unable to calculate team busyness, due %w", err)
+		return nil, nil, nil, fmt.Errorf("unable to calculate team busyness, due %w", err)
 	}
 
 	// Log the busyness report.
-	log.Printf("Team members by busyness: %q", busynessPerTeamMember.String())
+	level.Info(a.logger()).Log("msg", "team members by busyness", "report", busynessPerTeamMember.String())
 
 	// 2. Iterate over team members by increasing busyness and check their availability
-	var availableMembers []MemberConfig
+	foundAvailable := false
 	for _, b := range busynessPerTeamMember {
 		for _, name := range b.Users {
 			// find MemberConfig
@@ -95,61 +332,83 @@ func (a *Action) Run(ctx context.Context, event *github.IssuesEvent, labelsInput
 				continue
 			}
 
-			isAvailable, err := checkAvailability(member, a.Config.UnavailabilityLimit)
+			candidate := Candidate{Name: name, Tier: t.name, Busyness: b.Busyness}
+
+			if limit := team.capacity(member); limit > 0 && b.Busyness >= float64(limit) {
+				level.Info(a.logger()).Log("msg", "member is at capacity", "member", name, "busyness", b.Busyness, "limit", limit)
+				candidate.Availability = AvailabilityAtCapacity
+				candidate.Reason = fmt.Sprintf("capacity of %d issues reached", limit)
+				candidates = append(candidates, candidate)
+				continue
+			}
+
+			withCapacity = append(withCapacity, member)
+
+			if foundAvailable {
+				candidate.Availability = AvailabilityNotChecked
+				candidate.Reason = "less busy members are available"
+				candidates = append(candidates, candidate)
+				continue
+			}
+
+			isAvailable, err := a.availability(member)
 			if err != nil {
-				log.Printf("Unable to fetch availability of %q, due %v", name, err)
+				level.Warn(a.logger()).Log("msg", "unable to fetch availability", "member", name, "err", err)
+				candidate.Reason = redact.String(fmt.Sprintf("unable to fetch availability: %v", err))
 			}
 
 			if isAvailable {
-				availableMembers = append(availableMembers, member)
+				available = append(available, member)
+				candidate.Availability = AvailabilityAvailable
 			} else {
-				log.Printf("Member %q is not available based on calendar", name)
+				level.Info(a.logger()).Log("msg", "member is not available based on calendar", "member", name)
+				candidate.Availability = AvailabilityUnavailable
+				candidate.Reason = "busy due to a calendar event"
 			}
+			candidates = append(candidates, candidate)
 		}
 
-		// if we found available team members we can stop
-		if len(availableMembers) > 0 {
-			break
+		// if we found available team members we can stop checking availability
+		if len(available) > 0 {
+			foundAvailable = true
 		}
 	}
 
-	// In case no one is available we just consider everybody to be available
-	if len(availableMembers) == 0 {
-		log.Printf("Nobody seems to be available, hence we consider everybody to be available!")
-		availableMembers = teamMembers
-	}
-
-	// Log the available team members.
-	log.Printf("Available team members: %q", strings.Join(memberNames(availableMembers), ", "))
-
-	// choose a member
-	theChosenOne := availableMembers[rand.Intn(len(availableMembers))]
-	log.Printf("Chose member %q.\n", theChosenOne.Name)
+	return available, withCapacity, candidates, nil
+}
 
-	// set output
-	output := theChosenOne.Name
-	if theChosenOne.Output != "" {
-		output = theChosenOne.Output
+// report appends the assignment to the job summary of the workflow run and sets it as outputs of the action.
+// While assignments are collected, the outputs are set by reportAll instead.
+func (a *Action) report(assignment Assignment) {
+	if err := githubaction.AppendSummary(assignment.Markdown()); err != nil {
+		level.Warn(a.logger()).Log("msg", "unable to write job summary", "err", err)
 	}
 
-	githubaction.SetOutput("assignee", output)
-
-	if dryRun {
-		log.Print("Exiting because dry-run is enabled")
-		return nil
+	if a.assignments != nil {
+		a.assignments = append(a.assignments, assignment)
+		return
 	}
 
-	if event.Repo == nil || event.Repo.Name == nil {
-		log.Fatalf("Can't set any assignee as the repository or its name is missing, payload: %+v", event.Repo)
+	outputs, err := assignment.Outputs()
+	if err == nil {
+		err = githubaction.SetOutputs(outputs)
 	}
-
-	if event.Repo.Owner == nil || event.Repo.Owner.Login == nil {
-		log.Fatalf("Can't set any assignee as the repository owner or its login name is missing, payload: %+v", event.Repo.Owner)
+	if err != nil {
+		level.Warn(a.logger()).Log("msg", "unable to set outputs", "err", err)
 	}
+}
 
-	_, _, err = a.Client.Issues.AddAssignees(ctx, *event.Repo.Owner.Login, *event.Repo.Name, *event.Issue.Number, []string{theChosenOne.Name})
+// reportAll sets the collected assignments as outputs of the action and stops collecting them.
+func (a *Action) reportAll() {
+	outputs, err := multiOutputs(a.assignments)
+	a.assignments = nil
 
-	return err
+	if err == nil {
+		err = githubaction.SetOutputs(outputs)
+	}
+	if err != nil {
+		level.Warn(a.logger()).Log("msg", "unable to set outputs", "err", err)
+	}
 }
 
 func memberNames(members []MemberConfig) (result []string) {
@@ -165,10 +424,31 @@ func (a *Action) calculateIssueBusynessPerTeamMember(ctx context.Context, now ti
 		team[i] = m.Name
 	}
 
-	return busyness.CalculateBusynessForTeam(ctx, now, a.Client, a.Config.IgnoredLabels, team)
+	allocations := make(map[string]float64, len(members))
+	for _, m := range members {
+		allocations[m.Name] = m.allocation()
+	}
+
+	return busyness.CalculateBusynessForTeam(ctx, a.logger(), now, a.Client, a.Config.IgnoredLabels, team, allocations)
+}
+
+// availability checks whether the member is available per calendar, using the cache if set.
+func (a *Action) availability(m MemberConfig) (bool, error) {
+	if a.availabilityCache == nil {
+		return checkAvailability(a.logger(), m, a.Config.UnavailabilityLimit)
+	}
+
+	key := strings.ToLower(m.Name)
+	if r, ok := a.availabilityCache[key]; ok {
+		return r.available, r.err
+	}
+
+	available, err := checkAvailability(a.logger(), m, a.Config.UnavailabilityLimit)
+	a.availabilityCache[key] = availabilityResult{available: available, err: err}
+	return available, err
 }
 
-func checkAvailability(m MemberConfig, unavailabilityLimit time.Duration) (bool, error) {
+func checkAvailability(logger log.Logger, m MemberConfig, unavailabilityLimit time.Duration) (bool, error) {
 	if m.GoogleCalendar != "" {
 		cfg, err := GetGoogleConfig()
 		if err != nil {
@@ -177,7 +457,7 @@ func checkAvailability(m MemberConfig, unavailabilityLimit time.Duration) (bool,
 		return calendar.CheckGoogleAvailability(cfg, m.GoogleCalendar, m.Name, time.Now(), unavailabilityLimit)
 	}
 
-	return calendar.CheckAvailability(m.IcalURL, m.Name, time.Now(), unavailabilityLimit)
+	return calendar.CheckAvailability(logger, m.IcalURL, m.Name, time.Now(), unavailabilityLimit)
 }
 
 func GetGoogleConfig() (calendar.GoogleConfigJSON, error) {
@@ -189,73 +469,115 @@ func GetGoogleConfig() (calendar.GoogleConfigJSON, error) {
 	return calendar.GoogleConfigJSON(clientSecret), nil
 }
 
-// findTeam finds the right team defined
-func findTeam(cfg Config, labels []string) ([]MemberConfig, string) {
-	labelMap := map[string]struct{}{}
-	for _, l := range labels {
-		labelMap[l] = struct{}{}
-	}
+// findTeam finds the team responsible for the issue. If multiple teams match, their tiers are merged into a single team.
+func findTeam(cfg Config, iss issue.Issue, now time.Time) (TeamConfig, string) {
+	teamNames := matchTeams(cfg, iss, now)
 
-	matchedTeams := []TeamConfig{}
-	teamNames := []string{}
-	for name, t := range cfg.Teams {
-		match := false
-		for _, l := range t.RequireLabel {
-			if _, ok := labelMap[l]; ok {
-				match = true
-				break
+	switch len(teamNames) {
+	case 0:
+		return TeamConfig{}, ""
+	case 1:
+		return cfg.Teams[teamNames[0]], teamNames[0]
+	default:
+		// if multiple teams match let's merge them tier by tier, members keep the highest tier they are part of
+		var merged TeamConfig
+		var seen []MemberConfig
+		mergeTier := func(dst *[]MemberConfig, members []MemberConfig) {
+			for _, m := range members {
+				if !isMember(seen, m.Name) {
+					seen = append(seen, m)
+					*dst = append(*dst, m)
+				}
 			}
 		}
 
-		if !match {
-			continue
+		for _, name := range teamNames {
+			mergeTier(&merged.Members, cfg.Teams[name].Members)
+		}
+		for _, name := range teamNames {
+			mergeTier(&merged.Secondary, cfg.Teams[name].Secondary)
+		}
+		for _, name := range teamNames {
+			mergeTier(&merged.Manager, cfg.Teams[name].Manager)
 		}
 
-		matchedTeams = append(matchedTeams, t)
-		teamNames = append(teamNames, name)
-	}
-
-	switch len(matchedTeams) {
-	case 0:
-		return nil, ""
-	case 1:
-		return matchedTeams[0].Members, teamNames[0]
-	default:
-		// if multiple teams match let's merge them
-		// choose team randomly
-		memberMap := map[string]MemberConfig{}
-		for _, t := range matchedTeams {
-			for _, m := range t.Members {
-				if _, ok := memberMap[m.Name]; !ok {
-					memberMap[m.Name] = m
+		// members of several teams are allocated to the merged team with the sum of their allocations
+		for _, tier := range [][]MemberConfig{merged.Members, merged.Secondary, merged.Manager} {
+			for i, m := range tier {
+				allocation := 0.0
+				for _, name := range teamNames {
+					for _, member := range cfg.Teams[name].allMembers() {
+						if strings.EqualFold(member.Name, m.Name) {
+							allocation += member.allocation()
+						}
+					}
 				}
+				tier[i].Allocation = min(allocation, 1)
 			}
 		}
 
-		members := make([]MemberConfig, 0, len(memberMap))
-		for _, m := range memberMap {
-			members = append(members, m)
+		teams := make([]TeamConfig, len(teamNames))
+		for i, name := range teamNames {
+			teams[i] = cfg.Teams[name]
 		}
+		mergeTeamSettings(&merged, teams)
 
 		name := fmt.Sprintf("Merged (%v)", strings.Join(teamNames, ", "))
-		return members, name
+		return merged, name
+	}
+}
+
+// mergeTeamSettings applies the settings of the teams to the merged team. The strictest maxOpenIssues applies, all other
+// settings are taken from the first team which configures them, together with the backup of that team.
+func mergeTeamSettings(merged *TeamConfig, teams []TeamConfig) {
+	for _, t := range teams {
+		if t.MaxOpenIssues > 0 && (merged.MaxOpenIssues == 0 || t.MaxOpenIssues < merged.MaxOpenIssues) {
+			merged.MaxOpenIssues = t.MaxOpenIssues
+		}
+
+		// the same team is used by Escalate, see escalationTeam
+		if t.AckTimeout > 0 && merged.AckTimeout == 0 {
+			merged.AckTimeout, merged.Escalation, merged.Backup = t.AckTimeout, t.Escalation, t.Backup
+		}
+	}
+
+	for _, t := range teams {
+		if t.OverCapacity == "" {
+			continue
+		}
+
+		merged.OverCapacity, merged.NeedsOwnerLabel = t.OverCapacity, t.NeedsOwnerLabel
+		if merged.Backup == "" {
+			merged.Backup = t.Backup
+		}
+		break
 	}
 }
 
-// convertLabels creates a string array with all label names from an array of github labels
-func convertLabels(labels []github.Label) []string {
-	labelStrings := make([]string, len(labels))
-	for i, l := range labels {
-		labelStrings[i] = *l.Name
+// matchTeams returns the names of all teams responsible for the issue, sorted by name.
+func matchTeams(cfg Config, iss issue.Issue, now time.Time) []string {
+	var names []string
+	for name, t := range cfg.Teams {
+		// teams without any condition would match every issue, which is never intended
+		if t.RequireLabel.IsZero() && t.Rule == "" {
+			continue
+		}
+
+		if !t.RequireLabel.Match(iss.Labels) || !cfg.Rules.Match(t.Rule, iss, now) {
+			continue
+		}
+
+		names = append(names, name)
 	}
 
-	return labelStrings
+	sort.Strings(names)
+	return names
 }
 
-func isTeamMemberAssigned(teamMembers []MemberConfig, assignees []*github.User) (bool, string) {
+func isTeamMemberAssigned(teamMembers []MemberConfig, assignees []string) (bool, string) {
 	for _, m := range teamMembers {
 		for _, a := range assignees {
-			if strings.ToLower(a.GetLogin()) == strings.ToLower(m.Name) {
+			if strings.ToLower(a) == strings.ToLower(m.Name) {
 				return true, m.Name
 			}
 		}
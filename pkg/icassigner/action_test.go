@@ -17,21 +17,32 @@
 package icassigner
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+	"github.com/grafana/escalation-scheduler/pkg/rules"
 )
 
 func TestFindTeam(t *testing.T) {
 	teams := []TeamConfig{
 		{
-			RequireLabel: []string{"A", "B"},
+			RequireLabel: labelexpr.AnyOf("A", "B"),
 			Members:      []MemberConfig{{Name: "1"}, {Name: "2"}},
 		},
 		{
-			RequireLabel: []string{"C", "D"},
+			RequireLabel: labelexpr.AnyOf("C", "D"),
 			Members:      []MemberConfig{{Name: "3"}, {Name: "4"}},
 		},
 	}
@@ -72,7 +83,8 @@ func TestFindTeam(t *testing.T) {
 			for i, team := range testCase.teams {
 				cfg.Teams[fmt.Sprintf("%v", i)] = team
 			}
-			result, _ := findTeam(cfg, testCase.inputLabels)
+			team, _ := findTeam(cfg, issue.Issue{Labels: testCase.inputLabels}, time.Now())
+			result := team.Members
 
 			if len(testCase.expectedTeamMemberNames) == 0 && len(result) > 0 {
 				t.Error("Expected to have no team members matching, but got", len(result))
@@ -97,23 +109,63 @@ func TestFindTeam(t *testing.T) {
 	}
 }
 
+func TestFindTeam_MergesSettings(t *testing.T) {
+	cfg := Config{
+		Teams: map[string]TeamConfig{
+			"team-a": {
+				RequireLabel:  labelexpr.Label("label-a"),
+				Members:       []MemberConfig{{Name: "alice"}},
+				MaxOpenIssues: 5,
+			},
+			"team-b": {
+				RequireLabel:    labelexpr.Label("label-b"),
+				Members:         []MemberConfig{{Name: "bob"}},
+				MaxOpenIssues:   3,
+				OverCapacity:    OverCapacityLabel,
+				NeedsOwnerLabel: "triage",
+			},
+			"team-c": {
+				RequireLabel: labelexpr.Label("label-c"),
+				Members:      []MemberConfig{{Name: "carol"}},
+				AckTimeout:   time.Hour,
+				Escalation:   EscalateBoth,
+				Backup:       "grafana/leads",
+				OverCapacity: OverCapacityEscalate,
+			},
+		},
+	}
+
+	team, _ := findTeam(cfg, issue.Issue{Labels: []string{"label-a", "label-b", "label-c"}}, time.Now())
+
+	if team.MaxOpenIssues != 3 {
+		t.Errorf("expected the strictest maxOpenIssues 3, got %d", team.MaxOpenIssues)
+	}
+	if team.OverCapacity != OverCapacityLabel || team.NeedsOwnerLabel != "triage" {
+		t.Errorf("expected the overCapacity policy of team-b, got %q with label %q", team.OverCapacity, team.NeedsOwnerLabel)
+	}
+	if team.AckTimeout != time.Hour || team.Escalation != EscalateBoth || team.Backup != "grafana/leads" {
+		t.Errorf("expected the escalation settings of team-c, got %v %q %q", team.AckTimeout, team.Escalation, team.Backup)
+	}
+}
+
 func TestFindTeam_MultipleTeamsMatch(t *testing.T) {
 	// When two teams both match the issue labels, their members should be merged
 	// with duplicates deduplicated (alice appears in both teams).
 	cfg := Config{
 		Teams: map[string]TeamConfig{
 			"team-a": {
-				RequireLabel: []string{"label-a"},
+				RequireLabel: labelexpr.Label("label-a"),
 				Members:      []MemberConfig{{Name: "alice"}, {Name: "bob"}},
 			},
 			"team-b": {
-				RequireLabel: []string{"label-b"},
+				RequireLabel: labelexpr.Label("label-b"),
 				Members:      []MemberConfig{{Name: "charlie"}, {Name: "alice"}},
 			},
 		},
 	}
 
-	members, teamName := findTeam(cfg, []string{"label-a", "label-b"})
+	team, teamName := findTeam(cfg, issue.Issue{Labels: []string{"label-a", "label-b"}}, time.Now())
+	members := team.Members
 
 	// alice is deduped, so we expect alice + bob + charlie = 3 members.
 	if len(members) != 3 {
@@ -138,39 +190,119 @@ func TestFindTeam_MultipleTeamsMatch(t *testing.T) {
 	}
 }
 
-func TestConvertLabels(t *testing.T) {
-	testCases := []struct {
-		name     string
-		labels   []github.Label
-		expected []string
-	}{
-		{
-			name: "converts label names",
-			labels: []github.Label{
-				{Name: github.String("bug")},
-				{Name: github.String("enhancement")},
+func TestFindTeam_LabelExpression(t *testing.T) {
+	cfg := Config{
+		Teams: map[string]TeamConfig{
+			"important": {
+				RequireLabel: labelexpr.Expr{
+					All:  []labelexpr.Expr{labelexpr.Label("product"), labelexpr.AnyOf("important", "urgent")},
+					None: []labelexpr.Expr{labelexpr.Label("stale")},
+				},
+				Members: []MemberConfig{{Name: "manager"}},
+			},
+			"without-labels": {
+				Members: []MemberConfig{{Name: "nobody"}},
 			},
-			expected: []string{"bug", "enhancement"},
 		},
-		{
-			name:     "empty input returns empty slice",
-			labels:   []github.Label{},
-			expected: []string{},
+	}
+
+	team, teamName := findTeam(cfg, issue.Issue{Labels: []string{"product", "urgent"}}, time.Now())
+	members := team.Members
+	if len(members) != 1 || members[0].Name != "manager" || teamName != "important" {
+		t.Errorf("expected team important to match, got %q with %v", teamName, members)
+	}
+
+	for _, labels := range [][]string{{"product"}, {"product", "urgent", "stale"}, {}} {
+		if team, teamName := findTeam(cfg, issue.Issue{Labels: labels}, time.Now()); len(team.Members) != 0 {
+			t.Errorf("expected no team to match labels %v, got %q with %v", labels, teamName, team.Members)
+		}
+	}
+}
+
+func TestFindTeam_Rule(t *testing.T) {
+	cfg := Config{
+		Rules: rules.Set{
+			"external-loki-bugs": {
+				Labels:            labelexpr.Label("bug"),
+				AuthorAssociation: []string{"NONE", "CONTRIBUTOR"},
+				Body:              `(?i)loki`,
+			},
+		},
+		Teams: map[string]TeamConfig{
+			"loki": {
+				Rule:    "external-loki-bugs",
+				Members: []MemberConfig{{Name: "alice"}},
+			},
 		},
 	}
+	if err := cfg.Rules.Validate(); err != nil {
+		t.Fatal("unexpected error validating rules:", err)
+	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result := convertLabels(tc.labels)
-			if len(result) != len(tc.expected) {
-				t.Fatalf("expected %d labels, got %d: %v", len(tc.expected), len(result), result)
-			}
-			for i, expected := range tc.expected {
-				if result[i] != expected {
-					t.Errorf("expected label[%d] = %q, got %q", i, expected, result[i])
-				}
-			}
-		})
+	team, _ := findTeam(cfg, issue.Issue{Labels: []string{"bug"}, AuthorAssociation: "CONTRIBUTOR", Body: "Loki crashes"}, time.Now())
+	members := team.Members
+	if len(members) != 1 || members[0].Name != "alice" {
+		t.Errorf("expected team loki to match, got %v", members)
+	}
+
+	team, _ = findTeam(cfg, issue.Issue{Labels: []string{"bug"}, AuthorAssociation: "MEMBER", Body: "Loki crashes"}, time.Now())
+	members = team.Members
+	if len(members) != 0 {
+		t.Errorf("expected no team to match issues of members, got %v", members)
+	}
+}
+
+func TestFindTeam_MergesTiers(t *testing.T) {
+	cfg := Config{
+		Teams: map[string]TeamConfig{
+			"team-a": {
+				RequireLabel: labelexpr.Label("label-a"),
+				Members:      []MemberConfig{{Name: "alice"}},
+				Manager:      []MemberConfig{{Name: "bob"}},
+			},
+			"team-b": {
+				RequireLabel: labelexpr.Label("label-b"),
+				Members:      []MemberConfig{{Name: "bob"}},
+				Secondary:    []MemberConfig{{Name: "charlie"}},
+			},
+		},
+	}
+
+	team, _ := findTeam(cfg, issue.Issue{Labels: []string{"label-a", "label-b"}}, time.Now())
+
+	// members keep the highest tier they are part of
+	if names := memberNames(team.Members); strings.Join(names, ",") != "alice,bob" {
+		t.Errorf("expected primary tier alice,bob, got %v", names)
+	}
+	if names := memberNames(team.Secondary); strings.Join(names, ",") != "charlie" {
+		t.Errorf("expected secondary tier charlie, got %v", names)
+	}
+	if len(team.Manager) != 0 {
+		t.Errorf("expected empty manager tier, got %v", team.Manager)
+	}
+}
+
+func TestFindTeam_SumsAllocations(t *testing.T) {
+	cfg := Config{
+		Teams: map[string]TeamConfig{
+			"team-a": {
+				RequireLabel: labelexpr.Label("label-a"),
+				Members:      []MemberConfig{{Name: "alice", Allocation: 0.5}, {Name: "bob", Allocation: 0.3}, {Name: "carol"}},
+			},
+			"team-b": {
+				RequireLabel: labelexpr.Label("label-b"),
+				Members:      []MemberConfig{{Name: "alice", Allocation: 0.25}, {Name: "carol"}},
+			},
+		},
+	}
+
+	team, _ := findTeam(cfg, issue.Issue{Labels: []string{"label-a", "label-b"}}, time.Now())
+
+	expected := map[string]float64{"alice": 0.75, "bob": 0.3, "carol": 1}
+	for _, m := range team.Members {
+		if m.Allocation != expected[m.Name] {
+			t.Errorf("expected allocation %v for %q, got %v", expected[m.Name], m.Name, m.Allocation)
+		}
 	}
 }
 
@@ -184,42 +316,42 @@ func TestIsTeamMemberAssigned(t *testing.T) {
 	testCases := []struct {
 		name           string
 		teamMembers    []MemberConfig
-		assignees      []*github.User
+		assignees      []string
 		expectedResult bool
 		expectedName   string
 	}{
 		{
 			name:           "No assignees",
 			teamMembers:    teamMembers,
-			assignees:      []*github.User{},
+			assignees:      []string{},
 			expectedResult: false,
 			expectedName:   "",
 		},
 		{
 			name:           "Assignee matches team member",
 			teamMembers:    teamMembers,
-			assignees:      []*github.User{{Login: github.String("Alice")}},
+			assignees:      []string{"Alice"},
 			expectedResult: true,
 			expectedName:   "Alice",
 		},
 		{
 			name:           "Case-insensitive match",
 			teamMembers:    teamMembers,
-			assignees:      []*github.User{{Login: github.String("bob")}},
+			assignees:      []string{"bob"},
 			expectedResult: true,
 			expectedName:   "Bob",
 		},
 		{
 			name:           "No matching assignees",
 			teamMembers:    teamMembers,
-			assignees:      []*github.User{{Login: github.String("Unknown")}},
+			assignees:      []string{"Unknown"},
 			expectedResult: false,
 			expectedName:   "",
 		},
 		{
 			name:           "Multiple assignees, one matches",
 			teamMembers:    teamMembers,
-			assignees:      []*github.User{{Login: github.String("Unknown")}, {Login: github.String("Charlie")}},
+			assignees:      []string{"Unknown", "Charlie"},
 			expectedResult: true,
 			expectedName:   "Charlie",
 		},
@@ -239,3 +371,197 @@ func TestIsTeamMemberAssigned(t *testing.T) {
 		})
 	}
 }
+
+func TestCurrentOwners_IncludesRequestedReviewers(t *testing.T) {
+	iss := issue.Issue{
+		IsPullRequest:      true,
+		Assignees:          []string{"Alice"},
+		RequestedReviewers: []string{"Bob"},
+	}
+
+	assigned, name := isTeamMemberAssigned([]MemberConfig{{Name: "Bob"}}, currentOwners(iss))
+	if !assigned || name != "Bob" {
+		t.Errorf("expected requested reviewer Bob to count as assigned, got %v %q", assigned, name)
+	}
+}
+
+func TestWithoutMember(t *testing.T) {
+	members := withoutMember([]MemberConfig{{Name: "Alice"}, {Name: "Bob"}}, "alice")
+	if len(members) != 1 || members[0].Name != "Bob" {
+		t.Errorf("expected only Bob to remain, got %v", members)
+	}
+}
+
+func TestAssign_EscalatesToNextTier(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ooo.ics":
+			w.Write([]byte(outOfOfficeCalendar(time.Now())))
+		case "/free.ics":
+			w.Write([]byte("BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:test\nEND:VCALENDAR"))
+		case "/repos/owner/repo/issues":
+			w.Write([]byte(`[]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	summaryFile := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summaryFile)
+	outputFile := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+
+	a := &Action{Client: gh, Config: Config{UnavailabilityLimit: 6 * time.Hour}}
+
+	team := TeamConfig{
+		Members:   []MemberConfig{{Name: "alice", IcalURL: server.URL + "/ooo.ics"}},
+		Secondary: []MemberConfig{{Name: "bob", IcalURL: server.URL + "/ooo.ics"}},
+		Manager:   []MemberConfig{{Name: "carol", IcalURL: server.URL + "/free.ics"}},
+	}
+
+	chosen, err := a.assign(context.Background(), issue.Issue{Number: 1}, team, "loki", nil, nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chosen != "carol" {
+		t.Errorf("expected the manager tier to be chosen, got %q", chosen)
+	}
+
+	summary, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatalf("unable to read summary: %v", err)
+	}
+	for _, want := range []string{
+		"| alice | primary | 0 | unavailable | busy due to a calendar event |",
+		"| bob | secondary | 0 | unavailable | busy due to a calendar event |",
+		"| carol | manager | 0 | available |  |",
+		"Chosen member: **carol**",
+	} {
+		if !strings.Contains(string(summary), want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+
+	outputs := readOutputs(t, outputFile)
+	if outputs["assignee-login"] != "carol" || outputs["team"] != "loki" || outputs["fallback-used"] != "false" {
+		t.Errorf("unexpected outputs %v", outputs)
+	}
+
+	// nobody available at all falls back to the primary tier
+	team.Manager = []MemberConfig{{Name: "carol", IcalURL: server.URL + "/ooo.ics"}}
+	chosen, err = a.assign(context.Background(), issue.Issue{Number: 1}, team, "loki", nil, nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chosen != "alice" {
+		t.Errorf("expected to fall back to the primary tier, got %q", chosen)
+	}
+	if outputs := readOutputs(t, outputFile); outputs["fallback-used"] != "true" {
+		t.Errorf("expected the fallback to be reported, got %v", outputs)
+	}
+}
+
+func TestAssign_ExcludesAuthorOfPullRequest(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+	t.Setenv("GITHUB_OUTPUT", filepath.Join(t.TempDir(), "output"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/issues" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	a := &Action{Client: gh}
+	team := TeamConfig{Members: []MemberConfig{{Name: "alice"}, {Name: "bob"}, {Name: "carol"}}}
+
+	// spare capacity would let an append write into the backing array of the caller
+	exclude := make([]string, 1, 2)
+	exclude[0] = "carol"
+
+	chosen, err := a.assign(context.Background(), issue.Issue{Number: 1, IsPullRequest: true, Author: "alice"}, team, "loki", exclude, nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chosen != "bob" {
+		t.Errorf("expected neither the author nor excluded members to be chosen, got %q", chosen)
+	}
+	if spare := exclude[:2][1]; spare != "" {
+		t.Errorf("expected the exclude list of the caller to be left as it is, got %q appended", spare)
+	}
+}
+
+func TestRun_Capacity(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/free.ics":
+			w.Write([]byte("BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:test\nEND:VCALENDAR"))
+		case r.URL.Path == "/repos/owner/repo/issues" && r.URL.Query().Get("assignee") == "alice":
+			w.Write([]byte(`[{"number": 10, "state": "open"}, {"number": 11, "state": "open"}]`))
+		case r.URL.Path == "/repos/owner/repo/issues" && r.URL.Query().Get("assignee") == "bob":
+			w.Write([]byte(`[{"number": 12, "state": "open"}]`))
+		case r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			requests = append(requests, r.URL.Path+" "+strings.TrimSpace(string(body)))
+			if strings.HasSuffix(r.URL.Path, "/labels") {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	team := TeamConfig{
+		RequireLabel:  labelexpr.Label("loki"),
+		MaxOpenIssues: 2,
+		OverCapacity:  OverCapacityLabel,
+		Members: []MemberConfig{
+			{Name: "alice", IcalURL: server.URL + "/free.ics"},
+			{Name: "bob", IcalURL: server.URL + "/free.ics"},
+		},
+	}
+	a := &Action{Client: gh, Config: Config{UnavailabilityLimit: 6 * time.Hour, Teams: map[string]TeamConfig{"loki": team}}}
+	iss := issue.Issue{Owner: "owner", Repo: "repo", Number: 1, Labels: []string{"loki"}}
+
+	// alice is at capacity, so bob is chosen even though alice is available
+	if err := a.Run(context.Background(), iss, "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 || requests[0] != `/repos/owner/repo/issues/1/assignees {"assignees":["bob"]}` {
+		t.Errorf("expected bob to be assigned, got %q", requests)
+	}
+
+	// with everyone at capacity the issue is labeled instead
+	requests = nil
+	team.Members[1].MaxOpenIssues = 1
+	a.Config.Teams["loki"] = team
+	if err := a.Run(context.Background(), iss, "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 || requests[0] != `/repos/owner/repo/issues/1/labels ["needs-owner"]` {
+		t.Errorf("expected the needs-owner label to be added, got %q", requests)
+	}
+}
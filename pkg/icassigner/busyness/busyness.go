@@ -17,15 +17,18 @@
 package busyness
 
 import (
+	"cmp"
 	"context"
 	"fmt"
-	"log"
 	"slices"
 	"strings"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/google/go-github/github"
 	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
+	"github.com/grafana/escalation-scheduler/pkg/redact"
 )
 
 // Report represents the busyness of a team in ascending order of busyness
@@ -37,14 +40,15 @@ func (r Report) String() string {
 		if idx > 0 {
 			s += "; "
 		}
-		s += fmt.Sprintf("%d: %s\n", l.Busyness, strings.Join(l.Users, ","))
+		s += fmt.Sprintf("%g: %s\n", l.Busyness, strings.Join(l.Users, ","))
 	}
 	return s
 }
 
-// Level represents one level of busyness and all team members which match the given busyness
+// Level represents one level of busyness and all team members which match the given busyness.
+// Busyness is normalized by the allocation of the members, e.g. 2 issues of a member with an allocation of 0.5 are a busyness of 4.
 type Level struct {
-	Busyness int
+	Busyness float64
 	Users    []string
 }
 
@@ -54,21 +58,22 @@ type busynessClient interface {
 	getBusyness(ctx context.Context, since time.Time, user string) int
 }
 
-// CalculateBusynessForTeam calculates busyness of all members and returns a BusynessReport for them
-func CalculateBusynessForTeam(ctx context.Context, now time.Time, githubClient *github.Client, ignorableLabels []string, members []string) (Report, error) {
-	log.Printf("Calculating busyness for team members: %s\n", strings.Join(members, ", "))
+// CalculateBusynessForTeam calculates busyness of all members and returns a BusynessReport for them.
+// allocations contains the share of time members spend on the team, members without allocation are fully allocated.
+func CalculateBusynessForTeam(ctx context.Context, logger log.Logger, now time.Time, githubClient *github.Client, ignorableLabels []string, members []string, allocations map[string]float64) (Report, error) {
+	level.Info(logger).Log("msg", "calculating busyness for team members", "members", strings.Join(members, ", "))
 
-	bA, err := newGithubBusynessClient(githubClient, ignorableLabels)
+	bA, err := newGithubBusynessClient(logger, githubClient, ignorableLabels)
 	if err != nil {
 		return Report{}, fmt.Errorf("unable to create github busyness client, due %w", err)
 	}
-	return calculateBusynessForTeam(ctx, now, bA, members), nil
+	return calculateBusynessForTeam(ctx, now, bA, members, allocations), nil
 }
 
-func calculateBusynessForTeam(ctx context.Context, now time.Time, bA busynessClient, members []string) Report {
+func calculateBusynessForTeam(ctx context.Context, now time.Time, bA busynessClient, members []string, allocations map[string]float64) Report {
 	since := now.Add(-7 * 24 * time.Hour)
 
-	addMember := func(b map[int][]string, m string, busyness int) {
+	addMember := func(b map[float64][]string, m string, busyness float64) {
 		v, ok := b[busyness]
 		if !ok {
 			v = []string{m}
@@ -80,9 +85,15 @@ func calculateBusynessForTeam(ctx context.Context, now time.Time, bA busynessCli
 	}
 
 	// get busyness by team member
-	busyness := map[int][]string{}
+	busyness := map[float64][]string{}
 	for _, member := range members {
-		b := bA.getBusyness(ctx, since, member)
+		b := float64(bA.getBusyness(ctx, since, member))
+
+		// normalize by allocation, so that part-time members are as busy as full-time members with more issues
+		if allocation, ok := allocations[member]; ok && allocation > 0 {
+			b /= allocation
+		}
+
 		addMember(busyness, member, b)
 	}
 
@@ -94,7 +105,7 @@ func calculateBusynessForTeam(ctx context.Context, now time.Time, bA busynessCli
 
 	// sort in ascending order
 	slices.SortFunc[[]Level](report, func(a, b Level) int {
-		return a.Busyness - b.Busyness
+		return cmp.Compare(a.Busyness, b.Busyness)
 	})
 
 	return report
@@ -103,12 +114,13 @@ func calculateBusynessForTeam(ctx context.Context, now time.Time, bA busynessCli
 // githubBusynessClient is used to calculate busyness of users by the amount of github issues they are assigned to
 type githubBusynessClient struct {
 	labelsToIgnore map[string]struct{}
+	logger         log.Logger
 
 	listByAssigneeFunc func(ctx context.Context, since time.Time, assignee string, amount int) ([]*github.Issue, error)
 }
 
 // newGithubBusynessClient creates a new githubBusynessClient based on config.
-func newGithubBusynessClient(githubClient *github.Client, ignorableLabels []string) (*githubBusynessClient, error) {
+func newGithubBusynessClient(logger log.Logger, githubClient *github.Client, ignorableLabels []string) (*githubBusynessClient, error) {
 	labelsToIgnore := map[string]struct{}{}
 	for _, i := range ignorableLabels {
 		labelsToIgnore[i] = struct{}{}
@@ -133,6 +145,7 @@ func newGithubBusynessClient(githubClient *github.Client, ignorableLabels []stri
 
 	return &githubBusynessClient{
 		labelsToIgnore:     labelsToIgnore,
+		logger:             logger,
 		listByAssigneeFunc: listByAssigneeFunc,
 	}, nil
 }
@@ -144,7 +157,7 @@ func newGithubBusynessClient(githubClient *github.Client, ignorableLabels []stri
 func (b *githubBusynessClient) getBusyness(ctx context.Context, since time.Time, member string) int {
 	// check if one of the labels is contained by the labels to ignore
 
-	log.Printf("Calculating busyness of member %s based on their issues since %s\n", member, since.String())
+	level.Debug(b.logger).Log("msg", "calculating busyness of member based on their issues", "member", member, "since", since.String())
 
 	issues, err := b.listByAssigneeFunc(ctx, since, member, 20)
 	if err != nil {
@@ -158,23 +171,23 @@ func (b *githubBusynessClient) getBusyness(ctx context.Context, since time.Time,
 		case "open":
 			// check for labels to ignore, e.g. `stale` and ignore issue in this case
 			if b.containsLabelsToIgnore(i.Labels) {
-				log.Printf("%s: Ignoring open issue because it contains labels to ignore (%s): %s\n", member, labelsToString(i.Labels), i.GetTitle())
+				level.Debug(b.logger).Log("msg", "ignoring open issue because it contains labels to ignore", "member", member, "labels", labelsToString(i.Labels), "title", redact.Personal(i.GetTitle()))
 				continue
 			}
 
 			// increase busyness count otherwise
-			log.Printf("%s: Issue increases busyness because it is still open: %s\n", member, i.GetTitle())
+			level.Debug(b.logger).Log("msg", "issue increases busyness because it is still open", "member", member, "title", redact.Personal(i.GetTitle()))
 			busyness++
 		case "closed":
 			// if the issue got closed since our time to check
 			if since.Before(i.GetClosedAt()) {
-				log.Printf("%s: Issue increases busyness because it has been closed at %s which is after %s: %s\n", member, i.GetClosedAt().String(), since.String(), i.GetTitle())
+				level.Debug(b.logger).Log("msg", "issue increases busyness because it has been closed after since", "member", member, "closedAt", i.GetClosedAt().String(), "since", since.String(), "title", redact.Personal(i.GetTitle()))
 				busyness++
 			} else {
-				log.Printf("%s: Issue doesn't increase busyness because it has been closed at %s which is before %s: %s\n", member, i.GetClosedAt().String(), since.String(), i.GetTitle())
+				level.Debug(b.logger).Log("msg", "issue doesn't increase busyness because it has been closed before since", "member", member, "closedAt", i.GetClosedAt().String(), "since", since.String(), "title", redact.Personal(i.GetTitle()))
 			}
 		default:
-			log.Printf("%s: Issue doesn't increase busyness because it has an unknown state (%s): %s\n", member, i.GetState(), i.GetTitle())
+			level.Debug(b.logger).Log("msg", "issue doesn't increase busyness because it has an unknown state", "member", member, "state", i.GetState(), "title", redact.Personal(i.GetTitle()))
 		}
 	}
 
@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
 	"github.com/google/go-github/github"
 )
 
@@ -41,6 +42,7 @@ func TestCalculateIssueBusynessPerTeamMember(t *testing.T) {
 
 		time              time.Time
 		busynessPerMember map[string]int
+		allocations       map[string]float64
 
 		expectedReport Report
 	}{
@@ -70,6 +72,23 @@ func TestCalculateIssueBusynessPerTeamMember(t *testing.T) {
 				Level{Busyness: 3, Users: []string{members[0], members[2]}},
 			},
 		},
+		{
+			name: "TestBusynessIsNormalizedByAllocation",
+			time: now,
+			busynessPerMember: map[string]int{
+				members[0]: 2,
+				members[1]: 3,
+				members[2]: 3,
+			},
+			allocations: map[string]float64{
+				members[0]: 0.5,
+				members[1]: 1,
+			},
+			expectedReport: Report{
+				Level{Busyness: 3, Users: []string{members[1], members[2]}},
+				Level{Busyness: 4, Users: []string{members[0]}},
+			},
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -79,7 +98,7 @@ func TestCalculateIssueBusynessPerTeamMember(t *testing.T) {
 				resultByMemberName: testcase.busynessPerMember,
 			}
 
-			report := calculateBusynessForTeam(ctx, testcase.time, busynessClient, members)
+			report := calculateBusynessForTeam(ctx, testcase.time, busynessClient, members, testcase.allocations)
 
 			if len(testcase.expectedReport) != len(report) {
 				t.Fatalf("Expected same levels of busyness of %v, but got %v", testcase.expectedReport, report)
@@ -203,6 +222,7 @@ func TestGithubBusynessClient_getBusyness(t *testing.T) {
 			ba := &githubBusynessClient{
 				listByAssigneeFunc: mock.ListByAssignee,
 				labelsToIgnore:     make(map[string]struct{}),
+				logger:             log.NewNopLogger(),
 			}
 
 			for _, v := range testcase.IgnoredLabels {
@@ -17,12 +17,16 @@
 package calendar
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/emersion/go-ical"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/escalation-scheduler/pkg/redact"
 )
 
 const DefaultUnavailabilityLimit = 6 * time.Hour
@@ -90,7 +94,7 @@ func parseStartEnd(e ical.Event, loc *time.Location) (time.Time, time.Time, erro
 	return start, end, nil
 }
 
-func checkEvents(events []ical.Event, name string, now time.Time, loc *time.Location, unavailabilityLimit time.Duration) (bool, error) {
+func checkEvents(logger log.Logger, events []ical.Event, name string, now time.Time, loc *time.Location, unavailabilityLimit time.Duration) (bool, error) {
 	availabilityChecker := newIcalAvailabilityChecker(now, unavailabilityLimit, loc)
 
 	for _, event := range events {
@@ -100,13 +104,13 @@ func checkEvents(events []ical.Event, name string, now time.Time, loc *time.Loca
 
 		start, end, err := parseStartEnd(event, loc)
 		if err != nil {
-			log.Printf("Unable to parse start/end of an event, due %v\n", err)
+			level.Warn(logger).Log("msg", "unable to parse start/end of an event", "err", err)
 			continue
 		}
 
 		// check original occurence
 		if availabilityChecker.isEventBlockingAvailability(start, end) {
-			log.Printf("calendar.isAvailableOn: person %q in %q is unavailable due to event from %q to %q\n", name, loc.String(), start, end)
+			level.Debug(logger).Log("msg", "member is unavailable due to event", "member", name, "location", loc.String(), "start", redact.Personal(start.String()), "end", redact.Personal(end.String()))
 			return false, nil
 		}
 
@@ -126,7 +130,7 @@ func checkEvents(events []ical.Event, name string, now time.Time, loc *time.Loca
 			end := o.Add(completeDuration)
 
 			if availabilityChecker.isEventBlockingAvailability(start, end) {
-				log.Printf(`calendar.isAvailableOn: person %q is unavailable due to event from %q to %q`, name, start, end)
+				level.Debug(logger).Log("msg", "member is unavailable due to recurring event", "member", name, "start", redact.Personal(start.String()), "end", redact.Personal(end.String()))
 				return false, nil
 			}
 		}
@@ -135,9 +139,14 @@ func checkEvents(events []ical.Event, name string, now time.Time, loc *time.Loca
 	return true, nil
 }
 
-func CheckAvailability(icalUrl string, name string, now time.Time, unavailabilityLimit time.Duration) (bool, error) {
+func CheckAvailability(logger log.Logger, icalUrl string, name string, now time.Time, unavailabilityLimit time.Duration) (bool, error) {
 	resp, err := http.Get(icalUrl)
 	if err != nil {
+		// the url can be secret, so it must not be part of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
 		return true, fmt.Errorf("unable to download ical file, due %w", err)
 	}
 	defer resp.Body.Close()
@@ -159,5 +168,5 @@ func CheckAvailability(icalUrl string, name string, now time.Time, unavailabilit
 		}
 	}
 
-	return checkEvents(cal.Events(), name, now, loc, unavailabilityLimit)
+	return checkEvents(logger, cal.Events(), name, now, loc, unavailabilityLimit)
 }
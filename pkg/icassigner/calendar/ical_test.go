@@ -25,6 +25,7 @@ import (
 
 	_ "time/tzdata"
 
+	"github.com/go-kit/log"
 	"github.com/stretchr/testify/require"
 )
 
@@ -232,7 +233,7 @@ END:VEVENT
 END:VCALENDAR`)
 
 	now := time.Date(2024, time.January, 11, 12, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -263,7 +264,7 @@ END:VCALENDAR`)
 
 	// now is 1 hour before the event starts — without TRANSPARENT it would block.
 	now := time.Date(2024, time.January, 11, 8, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -292,7 +293,7 @@ END:VEVENT
 END:VCALENDAR`)
 
 	now := time.Date(2024, time.January, 11, 8, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -324,7 +325,7 @@ END:VCALENDAR`)
 
 	// Thursday 2024-01-11 at 03:00 UTC: next weekly occurrence (09:00–17:00) is within 12h.
 	now := time.Date(2024, time.January, 11, 3, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -360,7 +361,7 @@ END:VEVENT
 END:VCALENDAR`)
 
 	now := time.Date(2024, time.January, 10, 9, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -400,7 +401,7 @@ END:VEVENT
 END:VCALENDAR`)
 
 	now := time.Date(2024, time.January, 11, 8, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	require.NoError(t, err)
 	require.False(t, available, "expected unavailable: 8h blocking event present alongside a short non-blocking one")
 }
@@ -427,7 +428,7 @@ END:VEVENT
 END:VCALENDAR`)
 
 	now := time.Date(2024, time.January, 11, 12, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	require.NoError(t, err)
 	require.True(t, available, "expected available (event in the past; nil location never reached)")
 }
@@ -456,7 +457,7 @@ END:VCALENDAR`)
 
 	now := time.Date(2024, time.January, 11, 8, 0, 0, 0, time.UTC)
 	require.Panics(t, func() {
-		CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit) //nolint:errcheck
+		CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit) //nolint:errcheck
 	}, "expected panic: time.Time.In(nil) when X-WR-TIMEZONE is absent and event would block")
 }
 
@@ -469,18 +470,27 @@ func TestCheckAvailability_NonTwoHundredResponse(t *testing.T) {
 	t.Cleanup(ts.Close)
 
 	now := time.Date(2024, time.January, 11, 12, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(ts.URL, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), ts.URL, "tester", now, DefaultUnavailabilityLimit)
 	require.Error(t, err, "expected an error for non-200 response")
 	require.True(t, available, "expected fail-open (available=true) on HTTP error")
 }
 
+func TestCheckAvailability_ErrorDoesNotContainURL(t *testing.T) {
+	// Private feed URLs contain a secret token, which must not end up in logs.
+	secretURL := "http://127.0.0.1:0/private/secret-token/basic.ics"
+
+	_, err := CheckAvailability(log.NewNopLogger(), secretURL, "tester", time.Now(), DefaultUnavailabilityLimit)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "secret-token")
+}
+
 func TestCheckAvailability_MalformedIcal(t *testing.T) {
 	// A response body that is not valid ical must return available=true (fail-open) and
 	// a non-nil error. Same fail-open contract as the HTTP error case above.
 	url := newIcalTestServer(t, `this is not valid ical content`)
 
 	now := time.Date(2024, time.January, 11, 12, 0, 0, 0, time.UTC)
-	available, err := CheckAvailability(url, "tester", now, DefaultUnavailabilityLimit)
+	available, err := CheckAvailability(log.NewNopLogger(), url, "tester", now, DefaultUnavailabilityLimit)
 	require.Error(t, err, "expected an error for malformed ical")
 	require.True(t, available, "expected fail-open (available=true) on parse error")
 }
@@ -515,7 +525,7 @@ END:VCALENDAR`
 	loc, _ := time.LoadLocation("UTC")
 	now := time.Date(2023, time.December, 07, 16, 0, 0, 0, loc)
 
-	r, err := CheckAvailability(ts.URL, "tester", now, DefaultUnavailabilityLimit)
+	r, err := CheckAvailability(log.NewNopLogger(), ts.URL, "tester", now, DefaultUnavailabilityLimit)
 
 	if err != nil {
 		t.Errorf("No error expected during basic ical check, but got %v", err)
@@ -0,0 +1,313 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-github/github"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	// CheckRunName is the name of the check run created by PublishCheckRun.
+	CheckRunName = "ic-assignment config"
+
+	// maxAnnotations is the amount of annotations GitHub accepts per request.
+	maxAnnotations = 50
+)
+
+// yamlErrorLine extracts the line of errors reported by the yaml decoder, e.g. "line 3: field foo not found in type ..."
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// CheckResult is the result of CheckConfig.
+type CheckResult struct {
+	// Errors make the config unusable or break the assignment to some members, e.g. unreachable calendars.
+	Errors []Finding
+
+	// Warnings are the findings of Config.Lint.
+	Warnings []Finding
+}
+
+// CheckConfig parses and lints the raw config at path like the validate command, after resolving its includes.
+// If the config is valid, it also fetches the calendars of all members and looks up their GitHub handles, so that
+// problems show up before the config is used. Calendars referenced by ical-url-env are only fetched if the environment
+// variable is set, failing lookups of GitHub handles are reported as warnings. All findings are located by line in the raw config, findings in
+// included configs at the closest line of the raw config.
+func CheckConfig(ctx context.Context, client *github.Client, owner, repo, ref, path string, raw []byte) (CheckResult, error) {
+	var result CheckResult
+
+	// check the config itself first, as line numbers of the merged config don't match the raw config
+	var own Config
+	if err := yaml.UnmarshalStrict(raw, &own); err != nil {
+		result.Errors = parseErrorFindings(err)
+		return result, nil
+	}
+
+	merged := raw
+	resolved := own.Extends != "" || len(own.Include) > 0
+	if resolved {
+		var err error
+		merged, err = ResolveIncludes(ctx, client, owner, repo, ref, path, raw)
+		if err != nil {
+			directive := directiveInclude
+			if len(own.Include) == 0 {
+				directive = directiveExtends
+			}
+
+			result.Errors = []Finding{{Path: []string{directive}, Message: err.Error()}}
+			locate(raw, result.Errors)
+			return result, nil
+		}
+	}
+
+	cfg, err := ParseConfig(bytes.NewReader(merged))
+	if err != nil {
+		result.Errors = parseErrorFindings(err)
+		if resolved {
+			// lines reported by the yaml decoder refer to the merged config
+			for i := range result.Errors {
+				result.Errors[i].Line = 0
+			}
+		}
+		locate(raw, result.Errors)
+		return result, nil
+	}
+
+	result.Warnings = cfg.Lint()
+
+	names := make([]string, 0, len(cfg.Teams))
+	for name := range cfg.Teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// members can be listed in multiple teams, they are only checked once
+	checked := map[string]bool{}
+	for _, name := range names {
+		for _, tier := range cfg.Teams[name].tiers() {
+			for idx, m := range tier.members {
+				login := strings.ToLower(m.Name)
+				if checked[login] {
+					continue
+				}
+				checked[login] = true
+
+				path := []string{"teams", name, tier.key, strconv.Itoa(idx)}
+
+				// lookups can fail for reasons unrelated to the config, e.g. rate limits, so they don't stop the check
+				exists, err := userExists(ctx, client, m.Name)
+				if err != nil {
+					result.Warnings = append(result.Warnings, Finding{Path: path, Message: err.Error()})
+				} else if !exists {
+					result.Errors = append(result.Errors, Finding{Path: path, Message: fmt.Sprintf("GitHub user %q doesn't exist", m.Name)})
+				}
+
+				if m.IcalURLEnv != "" {
+					// the secret is only available if the workflow passes it to the check as well
+					m.IcalURL = os.Getenv(m.IcalURLEnv)
+					if m.IcalURL == "" {
+						result.Warnings = append(result.Warnings, Finding{Path: path, Message: fmt.Sprintf("calendar of member %q is not checked, as the environment variable %q is not set", m.Name, m.IcalURLEnv)})
+						continue
+					}
+				}
+
+				if m.IcalURL == "" && m.GoogleCalendar == "" {
+					continue
+				}
+
+				if _, err := checkAvailability(log.NewNopLogger(), m, cfg.UnavailabilityLimit); err != nil {
+					result.Errors = append(result.Errors, Finding{Path: path, Message: fmt.Sprintf("calendar of member %q is not reachable: %v", m.Name, err)})
+				}
+			}
+		}
+	}
+
+	locate(raw, result.Errors)
+	locate(raw, result.Warnings)
+	return result, nil
+}
+
+// parseErrorFindings converts an error of ParseConfig into findings, one per error reported by the yaml decoder.
+func parseErrorFindings(err error) []Finding {
+	var pErr pathError
+	if errors.As(err, &pErr) {
+		return []Finding{{Path: pErr.path, Message: pErr.Error()}}
+	}
+
+	var msgs []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	findings := make([]Finding, 0, len(msgs))
+	for _, msg := range msgs {
+		f := Finding{Message: msg}
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			f.Line, _ = strconv.Atoi(m[1])
+			f.Message = m[2]
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// locate sets the line of all findings with a path, which are not located yet.
+func locate(raw []byte, findings []Finding) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(raw, &root); err != nil || len(root.Content) == 0 {
+		return
+	}
+
+	for i := range findings {
+		if findings[i].Line == 0 {
+			findings[i].Line = lineOf(root.Content[0], findings[i].Path)
+		}
+	}
+}
+
+// lineOf returns the line of the node at the path, or of its deepest ancestor found if the path doesn't exist.
+// Entries of mappings are located by their key.
+func lineOf(node *yamlv3.Node, path []string) int {
+	line := node.Line
+	for _, key := range path {
+		var next *yamlv3.Node
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yamlv3.SequenceNode:
+			if idx, err := strconv.Atoi(key); err == nil && idx >= 0 && idx < len(node.Content) {
+				next = node.Content[idx]
+				line = next.Line
+			}
+		}
+
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+func userExists(ctx context.Context, client *github.Client, login string) (bool, error) {
+	_, resp, err := client.Users.Get(ctx, login)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to look up GitHub user %q, due %w", login, err)
+	}
+	return true, nil
+}
+
+// checkRunRequest is the request to create a check run. go-github's CreateCheckRunOptions still uses the annotation fields
+// of the preview API, which aren't accepted anymore.
+type checkRunRequest struct {
+	Name       string         `json:"name"`
+	HeadSHA    string         `json:"head_sha"`
+	Status     string         `json:"status"`
+	Conclusion string         `json:"conclusion"`
+	Output     checkRunOutput `json:"output"`
+}
+
+type checkRunOutput struct {
+	Title       string               `json:"title"`
+	Summary     string               `json:"summary"`
+	Annotations []checkRunAnnotation `json:"annotations,omitempty"`
+}
+
+type checkRunAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Message         string `json:"message"`
+}
+
+// PublishCheckRun creates a completed check run for the commit, with an annotation of the config file at path per finding.
+// The check fails if there are errors and is neutral if there are only warnings.
+func PublishCheckRun(ctx context.Context, client *github.Client, owner, repo, sha, path string, result CheckResult) error {
+	req := checkRunRequest{
+		Name:       CheckRunName,
+		HeadSHA:    sha,
+		Status:     "completed",
+		Conclusion: "success",
+		Output: checkRunOutput{
+			Title:   fmt.Sprintf("%d errors, %d warnings", len(result.Errors), len(result.Warnings)),
+			Summary: fmt.Sprintf("Checked `%s`.", path),
+		},
+	}
+
+	switch {
+	case len(result.Errors) > 0:
+		req.Conclusion = "failure"
+	case len(result.Warnings) > 0:
+		req.Conclusion = "neutral"
+	}
+
+	for _, group := range []struct {
+		level    string
+		findings []Finding
+	}{{"failure", result.Errors}, {"warning", result.Warnings}} {
+		for _, f := range group.findings {
+			line := max(f.Line, 1)
+			req.Output.Annotations = append(req.Output.Annotations, checkRunAnnotation{
+				Path:            path,
+				StartLine:       line,
+				EndLine:         line,
+				AnnotationLevel: group.level,
+				Message:         f.Message,
+			})
+		}
+	}
+
+	if len(req.Output.Annotations) > maxAnnotations {
+		req.Output.Annotations = req.Output.Annotations[:maxAnnotations]
+		req.Output.Summary += fmt.Sprintf(" Only the first %d findings are annotated.", maxAnnotations)
+	}
+
+	httpReq, err := client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%s/%s/check-runs", owner, repo), req)
+	if err != nil {
+		return fmt.Errorf("unable to create check run request, due %w", err)
+	}
+
+	if _, err := client.Do(ctx, httpReq, nil); err != nil {
+		return fmt.Errorf("unable to create check run, due %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestCheckConfig(t *testing.T) {
+	calendar := outOfOfficeCalendar(time.Now())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alice.ics":
+			w.Write([]byte(calendar))
+		case "/users/alice", "/users/bob":
+			w.Write([]byte(`{"login": "someone"}`))
+		case "/users/ghost", "/missing.ics":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	raw := strings.ReplaceAll(`teams:
+  loki:
+    requireLabel: [loki]
+    members:
+      - name: alice
+        ical-url: SERVER/alice.ics
+      - name: bob
+        ical-url: SERVER/missing.ics
+    secondary:
+      - name: ghost
+`, "SERVER", server.URL)
+
+	result, err := CheckConfig(context.Background(), gh, "owner", "repo", "sha", ".github/assignment.yaml", []byte(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", result.Errors)
+	}
+	if f := result.Errors[0]; f.Line != 7 || !strings.HasPrefix(f.Message, `calendar of member "bob" is not reachable`) {
+		t.Errorf("expected unreachable calendar of bob in line 7, got %+v", f)
+	}
+	if f := result.Errors[1]; f.Line != 10 || f.Message != `GitHub user "ghost" doesn't exist` {
+		t.Errorf("expected unknown user ghost in line 10, got %+v", f)
+	}
+
+	if len(result.Warnings) != 1 || result.Warnings[0].Line != 10 {
+		t.Errorf("expected ghost without calendar to be reported in line 10, got %+v", result.Warnings)
+	}
+}
+
+func TestCheckConfig_UncheckedMembers(t *testing.T) {
+	calendar := outOfOfficeCalendar(time.Now())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alice.ics":
+			w.Write([]byte(calendar))
+		case "/users/alice", "/users/carol":
+			w.Write([]byte(`{"login": "someone"}`))
+		case "/users/bob":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message": "Server Error"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	t.Setenv("ALICE_ICAL", server.URL+"/alice.ics")
+	raw := `teams:
+  loki:
+    requireLabel: [loki]
+    members:
+      - name: alice
+        ical-url-env: ALICE_ICAL
+      - name: bob
+        googleCalendar: bob@example.com
+      - name: carol
+        ical-url-env: CAROL_ICAL
+`
+
+	result, err := CheckConfig(context.Background(), gh, "owner", "repo", "sha", ".github/assignment.yaml", []byte(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// bob's calendar can't be fetched without service account key
+	if len(result.Errors) != 1 || result.Errors[0].Line != 7 || !strings.HasPrefix(result.Errors[0].Message, `calendar of member "bob" is not reachable`) {
+		t.Errorf("expected only the unreachable calendar of bob in line 7, got %+v", result.Errors)
+	}
+
+	var warnings []string
+	for _, f := range result.Warnings {
+		warnings = append(warnings, fmt.Sprintf("%d: %s", f.Line, f.Message))
+	}
+	expected := []string{
+		`7: unable to look up GitHub user "bob", due GET ` + server.URL + `/users/bob: 500 Server Error []`,
+		`9: calendar of member "carol" is not checked, as the environment variable "CAROL_ICAL" is not set`,
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("expected warnings\n%v\ngot\n%v", strings.Join(expected, "\n"), strings.Join(warnings, "\n"))
+	}
+}
+
+func TestCheckConfig_ParseErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		raw  string
+		line int
+	}{
+		"unknown field": {raw: "teams:\n  loki:\n    required_labels: [loki]\n", line: 3},
+		"invalid team":  {raw: "teams:\n  loki:\n    requireLabel: [loki]\n    escalation: never\n", line: 2},
+		"syntax":        {raw: "teams:\n  loki: [\n", line: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := CheckConfig(context.Background(), nil, "owner", "repo", "sha", ".github/assignment.yaml", []byte(tc.raw))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result.Errors) != 1 || result.Errors[0].Line != tc.line {
+				t.Errorf("expected one error in line %d, got %+v", tc.line, result.Errors)
+			}
+		})
+	}
+}
+
+func TestPublishCheckRun(t *testing.T) {
+	var got checkRunRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/check-runs" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("unable to decode request: %v", err)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	err := PublishCheckRun(context.Background(), gh, "owner", "repo", "sha", ".github/assignment.yaml", CheckResult{
+		Warnings: []Finding{{Line: 4, Message: "team \"loki\" has no members"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := checkRunRequest{
+		Name:       CheckRunName,
+		HeadSHA:    "sha",
+		Status:     "completed",
+		Conclusion: "neutral",
+		Output: checkRunOutput{
+			Title:   "0 errors, 1 warnings",
+			Summary: "Checked `.github/assignment.yaml`.",
+			Annotations: []checkRunAnnotation{
+				{Path: ".github/assignment.yaml", StartLine: 4, EndLine: 4, AnnotationLevel: "warning", Message: "team \"loki\" has no members"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected check run %+v, got %+v", expected, got)
+	}
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/codeowners"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+)
+
+// codeOwnersPaths are the locations GitHub looks for a CODEOWNERS file, in order.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwnersConfig derives teams from the CODEOWNERS file of the repository.
+type CodeOwnersConfig struct {
+	// Path is the path of the CODEOWNERS file. If empty, the locations supported by GitHub are tried.
+	Path string `yaml:"path,omitempty"`
+
+	// Labels maps labels to a path in the repository. Issues with the label are assigned to the owners of the path.
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// codeOwnersTeamName returns the name of the team derived from CODEOWNERS for a label.
+func codeOwnersTeamName(label string) string {
+	return "codeowners:" + label
+}
+
+// ResolveCodeOwners adds a team for every label of the codeowners config. The team requires the label and its members
+// are the owners of the path the label maps to: users directly and teams with all their members. Members configured
+// explicitly in any other team keep their configuration, e.g. their calendar.
+func (c *Config) ResolveCodeOwners(ctx context.Context, logger log.Logger, client *github.Client, owner, repo, ref string) error {
+	if c.CodeOwners == nil || len(c.CodeOwners.Labels) == 0 {
+		return nil
+	}
+
+	file, err := fetchCodeOwners(ctx, client, owner, repo, ref, c.CodeOwners.Path)
+	if err != nil {
+		return err
+	}
+
+	known := map[string]MemberConfig{}
+	for _, t := range c.Teams {
+		for _, m := range t.allMembers() {
+			known[strings.ToLower(m.Name)] = m
+		}
+	}
+
+	// resolve teams only once, even if they own multiple paths
+	teamMembers := map[string][]string{}
+
+	labels := make([]string, 0, len(c.CodeOwners.Labels))
+	for l := range c.CodeOwners.Labels {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		p := c.CodeOwners.Labels[label]
+
+		var logins []string
+		for _, o := range file.Owners(p) {
+			if !strings.HasPrefix(o, "@") {
+				level.Warn(logger).Log("msg", "ignoring code owner, as only users and teams can be assigned", "owner", o, "path", p)
+				continue
+			}
+
+			org, slug, isTeam := strings.Cut(strings.TrimPrefix(o, "@"), "/")
+			if !isTeam {
+				logins = append(logins, org)
+				continue
+			}
+
+			members, ok := teamMembers[o]
+			if !ok {
+				members, err = listGithubTeamMembers(ctx, client, org, slug)
+				if err != nil {
+					return fmt.Errorf("unable to resolve code owner %q, due %w", o, err)
+				}
+				teamMembers[o] = members
+			}
+			logins = append(logins, members...)
+		}
+
+		team := TeamConfig{RequireLabel: labelexpr.Label(label)}
+		for _, login := range logins {
+			if isMember(team.Members, login) {
+				continue
+			}
+
+			m, ok := known[strings.ToLower(login)]
+			if !ok {
+				m = MemberConfig{Name: login}
+			}
+			team.Members = append(team.Members, m)
+		}
+
+		if len(team.Members) == 0 {
+			level.Warn(logger).Log("msg", "no code owners found for path", "path", p, "label", label)
+			continue
+		}
+
+		if c.Teams == nil {
+			c.Teams = map[string]TeamConfig{}
+		}
+		c.Teams[codeOwnersTeamName(label)] = team
+	}
+
+	return nil
+}
+
+func fetchCodeOwners(ctx context.Context, client *github.Client, owner, repo, ref, path string) (codeowners.File, error) {
+	paths := codeOwnersPaths
+	if path != "" {
+		paths = []string{path}
+	}
+
+	var lastErr error
+	for _, p := range paths {
+		content, err := FetchFile(ctx, client, owner, repo, ref, p)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		file, err := codeowners.Parse(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s, due %w", p, err)
+		}
+		return file, nil
+	}
+
+	return nil, fmt.Errorf("unable to find CODEOWNERS, due %w", lastErr)
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-github/github"
+)
+
+func TestResolveCodeOwners(t *testing.T) {
+	codeOwners := base64.StdEncoding.EncodeToString([]byte(`
+# default owners
+*                   @grafana/loki-squad
+/pkg/ingester/      @alice @bob docs@grafana.com
+`))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/contents/.github/CODEOWNERS":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+		case "/repos/owner/repo/contents/CODEOWNERS":
+			fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, codeOwners)
+		case "/orgs/grafana/teams/loki-squad/members":
+			w.Write([]byte(`[{"login": "carol"}, {"login": "alice"}]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	cfg, err := ParseConfig(strings.NewReader(`
+codeowners:
+  labels:
+    ingester: pkg/ingester/ingester.go
+    querier: pkg/querier/
+teams:
+  loki:
+    requireLabel: [loki]
+    members:
+      - name: alice
+        ical-url: https://example.com/alice.ics
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cfg.ResolveCodeOwners(context.Background(), log.NewNopLogger(), gh, "owner", "repo", "sha"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ingester := cfg.Teams["codeowners:ingester"]
+	if names := strings.Join(memberNames(ingester.Members), ","); names != "alice,bob" {
+		t.Errorf("expected members alice,bob, got %v", names)
+	}
+	if ingester.Members[0].IcalURL != "https://example.com/alice.ics" {
+		t.Errorf("expected configuration of alice to be reused, got %v", ingester.Members[0])
+	}
+	if !ingester.RequireLabel.Match([]string{"ingester"}) {
+		t.Error("expected team to require the ingester label")
+	}
+
+	querier := cfg.Teams["codeowners:querier"]
+	if names := strings.Join(memberNames(querier.Members), ","); names != "carol,alice" {
+		t.Errorf("expected members carol,alice, got %v", names)
+	}
+}
+
+func TestParseConfig_CodeOwnersConflict(t *testing.T) {
+	_, err := ParseConfig(strings.NewReader(`
+codeowners:
+  labels:
+    loki: pkg/
+teams:
+  codeowners:loki:
+    requireLabel: [loki]
+`))
+	if err == nil {
+		t.Error("expected team conflicting with a derived team to be rejected")
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+)
+
+const (
+	// CommandReassign assigns the issue to another member of the responsible team.
+	CommandReassign = "reassign"
+
+	// CommandAssignTeam assigns the issue to a member of the team given as argument.
+	CommandAssignTeam = "assign-team"
+
+	// CommandUnavailable lets the current assignee hand the issue over to another member of the team.
+	CommandUnavailable = "unavailable"
+)
+
+// Command is a slash command received as issue comment.
+type Command struct {
+	Name string
+	Args []string
+
+	// Sender is the login of the user who wrote the comment.
+	Sender string
+
+	// CommentID is the id of the comment, which is used to acknowledge the command with a reaction.
+	CommentID int64
+}
+
+// commandResult is what a command did, which is acknowledged by a reaction on the comment.
+type commandResult struct {
+	reaction string
+	reason   string
+}
+
+// RunCommand runs a slash command on the issue. Commands are only accepted from members of the teams involved,
+// accepted commands are acknowledged with a 👍 reaction and rejected commands with a 👎 reaction.
+// Unknown commands are ignored.
+func (a *Action) RunCommand(ctx context.Context, iss issue.Issue, cmd Command, dryRun bool) error {
+	var handle func(context.Context, issue.Issue, Command, bool) (commandResult, error)
+	switch cmd.Name {
+	case CommandReassign:
+		handle = a.reassign
+	case CommandAssignTeam:
+		handle = a.assignTeam
+	case CommandUnavailable:
+		handle = a.unavailable
+	default:
+		level.Info(a.logger()).Log("msg", "ignoring unknown command", "command", cmd.Name)
+		return nil
+	}
+
+	var result commandResult
+	var err error
+
+	// comments on pull requests don't contain the requested reviewers, who are responsible for them like assignees
+	if iss.IsPullRequest && len(iss.RequestedReviewers) == 0 {
+		iss.RequestedReviewers, err = a.listRequestedReviewers(ctx, iss)
+	}
+	if err == nil {
+		result, err = handle(ctx, iss, cmd, dryRun)
+	}
+
+	if err != nil {
+		result = commandResult{reaction: "confused", reason: err.Error()}
+	}
+
+	level.Info(a.logger()).Log("msg", "command handled", "command", cmd.Name, "sender", cmd.Sender, "result", result.reason)
+
+	if dryRun {
+		level.Info(a.logger()).Log("msg", "not reacting because dry-run is enabled", "reaction", result.reaction)
+		return err
+	}
+
+	if _, _, reactErr := a.Client.Reactions.CreateIssueCommentReaction(ctx, iss.Owner, iss.Repo, cmd.CommentID, result.reaction); reactErr != nil {
+		level.Warn(a.logger()).Log("msg", "unable to acknowledge command", "err", reactErr)
+	}
+
+	return err
+}
+
+func (a *Action) reassign(ctx context.Context, iss issue.Issue, cmd Command, dryRun bool) (commandResult, error) {
+	team, teamName := findTeam(a.Config, iss, time.Now())
+	if len(team.allMembers()) == 0 {
+		return rejected("no team is responsible for this issue"), nil
+	}
+
+	if !isMember(team.allMembers(), cmd.Sender) {
+		return rejected(fmt.Sprintf("%q is not a member of team %q", cmd.Sender, teamName)), nil
+	}
+
+	current := assignedMembers(team.allMembers(), currentOwners(iss))
+	chosen, err := a.assign(ctx, iss, team, teamName, current, current, dryRun)
+	if errors.Is(err, errAtCapacity) {
+		return rejected(fmt.Sprintf("every member of team %q is at capacity", teamName)), nil
+	}
+	if err != nil || chosen == "" {
+		return rejected(fmt.Sprintf("no other member of team %q is left", teamName)), err
+	}
+
+	return accepted(fmt.Sprintf("reassigned to %q of team %q", chosen, teamName)), nil
+}
+
+func (a *Action) assignTeam(ctx context.Context, iss issue.Issue, cmd Command, dryRun bool) (commandResult, error) {
+	if len(cmd.Args) != 1 {
+		return rejected(fmt.Sprintf("expected exactly one team, e.g. /%s <team>", CommandAssignTeam)), nil
+	}
+
+	target, ok := a.Config.Teams[cmd.Args[0]]
+	if !ok {
+		return rejected(fmt.Sprintf("unknown team %q", cmd.Args[0])), nil
+	}
+
+	// members of the team currently responsible can hand issues over, members of the target team can take them
+	currentTeam, _ := findTeam(a.Config, iss, time.Now())
+	if !isMember(currentTeam.allMembers(), cmd.Sender) && !isMember(target.allMembers(), cmd.Sender) {
+		return rejected(fmt.Sprintf("%q is neither a member of the responsible team nor of team %q", cmd.Sender, cmd.Args[0])), nil
+	}
+
+	current := assignedMembers(currentTeam.allMembers(), currentOwners(iss))
+	chosen, err := a.assign(ctx, iss, target, cmd.Args[0], nil, current, dryRun)
+	if errors.Is(err, errAtCapacity) {
+		return rejected(fmt.Sprintf("every member of team %q is at capacity", cmd.Args[0])), nil
+	}
+	if err != nil || chosen == "" {
+		return rejected(fmt.Sprintf("no member of team %q is left", cmd.Args[0])), err
+	}
+
+	return accepted(fmt.Sprintf("assigned to %q of team %q", chosen, cmd.Args[0])), nil
+}
+
+func (a *Action) unavailable(ctx context.Context, iss issue.Issue, cmd Command, dryRun bool) (commandResult, error) {
+	team, teamName := findTeam(a.Config, iss, time.Now())
+	if len(team.allMembers()) == 0 {
+		return rejected("no team is responsible for this issue"), nil
+	}
+
+	if !isMember(team.allMembers(), cmd.Sender) || !isMember([]MemberConfig{{Name: cmd.Sender}}, currentOwners(iss)...) {
+		return rejected(fmt.Sprintf("%q is not an assignee of this issue and a member of team %q", cmd.Sender, teamName)), nil
+	}
+
+	chosen, err := a.assign(ctx, iss, team, teamName, []string{cmd.Sender}, []string{cmd.Sender}, dryRun)
+	if errors.Is(err, errAtCapacity) {
+		return rejected(fmt.Sprintf("every member of team %q is at capacity", teamName)), nil
+	}
+	if err != nil || chosen == "" {
+		return rejected(fmt.Sprintf("no other member of team %q is left", teamName)), err
+	}
+
+	return accepted(fmt.Sprintf("handed over to %q of team %q", chosen, teamName)), nil
+}
+
+// listRequestedReviewers returns the logins of the users requested to review the pull request.
+func (a *Action) listRequestedReviewers(ctx context.Context, iss issue.Issue) ([]string, error) {
+	opts := &github.ListOptions{PerPage: 100}
+
+	var result []string
+	for {
+		reviewers, resp, err := a.Client.PullRequests.ListReviewers(ctx, iss.Owner, iss.Repo, iss.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list requested reviewers, due %w", err)
+		}
+
+		for _, u := range reviewers.Users {
+			result = append(result, u.GetLogin())
+		}
+
+		if resp.NextPage == 0 {
+			return result, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func accepted(reason string) commandResult {
+	return commandResult{reaction: "+1", reason: reason}
+}
+
+func rejected(reason string) commandResult {
+	return commandResult{reaction: "-1", reason: reason}
+}
+
+// isMember reports whether any of the logins is one of the members.
+func isMember(members []MemberConfig, logins ...string) bool {
+	for _, m := range members {
+		for _, login := range logins {
+			if strings.EqualFold(m.Name, login) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// assignedMembers returns the names of all members which are contained in assignees.
+func assignedMembers(members []MemberConfig, assignees []string) (result []string) {
+	for _, a := range assignees {
+		if isMember(members, a) {
+			result = append(result, a)
+		}
+	}
+	return result
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+)
+
+func TestRunCommand_Rejected(t *testing.T) {
+	cfg := Config{
+		Teams: map[string]TeamConfig{
+			"loki": {
+				RequireLabel: labelexpr.Label("loki"),
+				Members:      []MemberConfig{{Name: "alice"}, {Name: "bob"}},
+			},
+			"mimir": {
+				RequireLabel: labelexpr.Label("mimir"),
+				Members:      []MemberConfig{{Name: "carol"}},
+			},
+		},
+	}
+	iss := issue.Issue{Owner: "owner", Repo: "repo", Number: 1, Labels: []string{"loki"}, Assignees: []string{"alice"}}
+
+	testCases := []struct {
+		name string
+		cmd  Command
+	}{
+		{name: "reassign by non member", cmd: Command{Name: CommandReassign, Sender: "mallory"}},
+		{name: "assign-team without team", cmd: Command{Name: CommandAssignTeam, Sender: "alice"}},
+		{name: "assign-team to unknown team", cmd: Command{Name: CommandAssignTeam, Args: []string{"tempo"}, Sender: "alice"}},
+		{name: "assign-team by non member", cmd: Command{Name: CommandAssignTeam, Args: []string{"mimir"}, Sender: "mallory"}},
+		{name: "unavailable by member who isn't assigned", cmd: Command{Name: CommandUnavailable, Sender: "bob"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			gh := github.NewClient(nil)
+			gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+			tc.cmd.CommentID = 42
+			a := &Action{Client: gh, Config: cfg}
+			if err := a.RunCommand(context.Background(), iss, tc.cmd, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			expected := "POST /repos/owner/repo/issues/comments/42/reactions {\"content\":\"-1\"}\n"
+			if len(requests) != 1 || requests[0] != expected {
+				t.Errorf("expected only a -1 reaction, got %q", requests)
+			}
+		})
+	}
+}
+
+func TestRunCommand_UnknownCommandIsIgnored(t *testing.T) {
+	a := &Action{}
+	if err := a.RunCommand(context.Background(), issue.Issue{}, Command{Name: "unknown"}, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAssignedMembers(t *testing.T) {
+	members := []MemberConfig{{Name: "Alice"}, {Name: "Bob"}}
+
+	result := assignedMembers(members, []string{"alice", "carol"})
+	if len(result) != 1 || result[0] != "alice" {
+		t.Errorf("expected only alice to be an assigned member, got %v", result)
+	}
+}
+
+func TestRunCommand_AssignTeamKeepsChosenAssignee(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/issues":
+			w.Write([]byte(`[]`))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	// alice is responsible as member of loki and the only member of mimir
+	cfg := Config{
+		Teams: map[string]TeamConfig{
+			"loki":  {RequireLabel: labelexpr.Label("loki"), Members: []MemberConfig{{Name: "alice"}, {Name: "bob"}}},
+			"mimir": {RequireLabel: labelexpr.Label("mimir"), Members: []MemberConfig{{Name: "alice"}}},
+		},
+	}
+	iss := issue.Issue{Owner: "owner", Repo: "repo", Number: 1, Labels: []string{"loki"}, Assignees: []string{"alice"}}
+
+	a := &Action{Client: gh, Config: cfg}
+	cmd := Command{Name: CommandAssignTeam, Args: []string{"mimir"}, Sender: "alice", CommentID: 42}
+	if err := a.RunCommand(context.Background(), iss, cmd, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, r := range requests {
+		if r == "DELETE /repos/owner/repo/issues/1/assignees {\"assignees\":[\"alice\"]}\n" {
+			t.Errorf("expected alice to stay assigned, got %q", requests)
+		}
+	}
+	if len(requests) != 2 || requests[1] != "POST /repos/owner/repo/issues/comments/42/reactions {\"content\":\"+1\"}\n" {
+		t.Errorf("expected alice to be assigned and the command to be accepted, got %q", requests)
+	}
+}
+
+func TestRunCommand_ReassignPullRequest(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/issues":
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/pulls/1/requested_reviewers":
+			w.Write([]byte(`{"users": [{"login": "alice"}], "teams": []}`))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	cfg := Config{
+		Teams: map[string]TeamConfig{
+			"loki": {RequireLabel: labelexpr.Label("loki"), Members: []MemberConfig{{Name: "alice"}, {Name: "bob"}}},
+		},
+	}
+	// issue_comment events don't contain the requested reviewers of pull requests
+	iss := issue.Issue{Owner: "owner", Repo: "repo", Number: 1, Labels: []string{"loki"}, IsPullRequest: true, Author: "carol"}
+
+	a := &Action{Client: gh, Config: cfg}
+	cmd := Command{Name: CommandReassign, Sender: "bob", CommentID: 42}
+	if err := a.RunCommand(context.Background(), iss, cmd, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"POST /repos/owner/repo/pulls/1/requested_reviewers {\"reviewers\":[\"bob\"]}\n",
+		"DELETE /repos/owner/repo/pulls/1/requested_reviewers {\"reviewers\":[\"alice\"]}\n",
+		"POST /repos/owner/repo/issues/comments/42/reactions {\"content\":\"+1\"}\n",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected the requested reviewer to be replaced, got %q", requests)
+	}
+}
@@ -21,10 +21,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/grafana/escalation-scheduler/pkg/icassigner/calendar"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+	"github.com/grafana/escalation-scheduler/pkg/rules"
 	"gopkg.in/yaml.v2"
 )
 
@@ -32,11 +35,155 @@ type Config struct {
 	UnavailabilityLimit time.Duration         `yaml:"unavailabilityLimit,omitempty"`
 	Teams               map[string]TeamConfig `yaml:"teams,omitempty"`
 	IgnoredLabels       []string              `yaml:"ignoreLabels,omitempty"`
+
+	// RebalanceLookback limits the rebalance mode to issues assigned within this duration, defaults to 7 days.
+	RebalanceLookback time.Duration `yaml:"rebalanceLookback,omitempty"`
+
+	// Rules defines named rules which can be referenced by teams.
+	Rules rules.Set `yaml:"rules,omitempty"`
+
+	// CodeOwners derives additional teams from the CODEOWNERS file, see Config.ResolveCodeOwners.
+	CodeOwners *CodeOwnersConfig `yaml:"codeowners,omitempty"`
+
+	// Extends and Include reference configs this config is merged with, see ResolveIncludes.
+	// They are resolved by FetchConfig and always empty after parsing a fetched config.
+	Extends string   `yaml:"extends,omitempty"`
+	Include []string `yaml:"include,omitempty"`
 }
 
 type TeamConfig struct {
-	RequireLabel []string       `yaml:"requireLabel,omitempty"`
-	Members      []MemberConfig `yaml:"members,omitempty"`
+	// RequireLabel is a label expression an issue needs to match to be assigned to this team.
+	// The legacy list form requires at least one of the listed labels. Teams without requireLabel never match.
+	RequireLabel labelexpr.Expr `yaml:"requireLabel,omitempty"`
+
+	// Rule is the name of a rule an issue needs to match to be assigned to this team, in addition to RequireLabel.
+	Rule string `yaml:"rule,omitempty"`
+
+	// Members is the primary tier of the team, issues are assigned to them as long as any of them is available.
+	Members []MemberConfig `yaml:"members,omitempty"`
+
+	// GithubTeam is a GitHub team ("org/team-slug") whose members are added to Members at run time, see Config.ResolveGithubTeams.
+	GithubTeam string `yaml:"githubTeam,omitempty"`
+
+	// Exclude lists members which are never assigned, e.g. members of the GitHub team who don't take issues.
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	// Secondary is the tier issues are assigned to if no member of the primary tier is available.
+	Secondary []MemberConfig `yaml:"secondary,omitempty"`
+
+	// Manager is the last tier issues are assigned to if neither the primary nor the secondary tier is available.
+	Manager []MemberConfig `yaml:"manager,omitempty"`
+
+	// AckTimeout is the time an assignee has to acknowledge an issue before it's escalated by the escalate mode.
+	// Issues of teams without ackTimeout are never escalated.
+	AckTimeout time.Duration `yaml:"ackTimeout,omitempty"`
+
+	// Backup is mentioned on issues which haven't been acknowledged in time, e.g. "someone" or "grafana/loki-leads".
+	Backup string `yaml:"backup,omitempty"`
+
+	// Escalation defines what happens to unacknowledged issues, either "reassign" (default), "backup" or "both".
+	Escalation string `yaml:"escalation,omitempty"`
+
+	// MaxOpenIssues is the busyness at which members are at capacity and skipped like unavailable members.
+	// It applies to all members without their own maxOpenIssues. Unlimited if 0.
+	MaxOpenIssues int `yaml:"maxOpenIssues,omitempty"`
+
+	// OverCapacity defines what happens if every member is at capacity, either "assign" (default) to assign anyway,
+	// "escalate" to mention the backup or "label" to leave the issue unassigned with the NeedsOwnerLabel.
+	OverCapacity string `yaml:"overCapacity,omitempty"`
+
+	// NeedsOwnerLabel is added to issues left unassigned by the "label" policy, defaults to "needs-owner".
+	NeedsOwnerLabel string `yaml:"needsOwnerLabel,omitempty"`
+}
+
+// tier is an ordered group of members of a team.
+type tier struct {
+	name    string
+	key     string // key of the tier in the config
+	members []MemberConfig
+}
+
+// tiers returns the tiers of the team in the order they are tried when assigning issues.
+func (t TeamConfig) tiers() []tier {
+	return []tier{
+		{name: "primary", key: "members", members: t.Members},
+		{name: "secondary", key: "secondary", members: t.Secondary},
+		{name: "manager", key: "manager", members: t.Manager},
+	}
+}
+
+// allMembers returns the members of all tiers, each member only once.
+func (t TeamConfig) allMembers() []MemberConfig {
+	var result []MemberConfig
+	for _, tier := range t.tiers() {
+		for _, m := range tier.members {
+			if !isMember(result, m.Name) {
+				result = append(result, m)
+			}
+		}
+	}
+	return result
+}
+
+func (t TeamConfig) validate() error {
+	switch t.Escalation {
+	case "", EscalateReassign:
+	case EscalateBackup, EscalateBoth:
+		if t.Backup == "" {
+			return fmt.Errorf("escalation %q requires backup to be set", t.Escalation)
+		}
+	default:
+		return fmt.Errorf("unknown escalation %q, expected %q, %q or %q", t.Escalation, EscalateReassign, EscalateBackup, EscalateBoth)
+	}
+
+	if t.MaxOpenIssues < 0 {
+		return fmt.Errorf("maxOpenIssues must not be negative, but got %d", t.MaxOpenIssues)
+	}
+
+	if t.AckTimeout < 0 {
+		return fmt.Errorf("ackTimeout must not be negative, but got %v", t.AckTimeout)
+	}
+
+	if t.GithubTeam != "" {
+		if org, slug, ok := strings.Cut(t.GithubTeam, "/"); !ok || org == "" || slug == "" || strings.Contains(slug, "/") {
+			return fmt.Errorf("githubTeam must be in the form org/team-slug, but got %q", t.GithubTeam)
+		}
+	}
+
+	for _, m := range t.allMembers() {
+		if m.Allocation < 0 || m.Allocation > 1 {
+			return fmt.Errorf("allocation of member %q must be between 0 and 1, but got %v", m.Name, m.Allocation)
+		}
+
+		if m.MaxOpenIssues < 0 {
+			return fmt.Errorf("maxOpenIssues of member %q must not be negative, but got %d", m.Name, m.MaxOpenIssues)
+		}
+
+		if m.IcalURL != "" && m.IcalURLEnv != "" {
+			return fmt.Errorf("member %q can't have both ical-url and ical-url-env", m.Name)
+		}
+	}
+
+	switch t.OverCapacity {
+	case "", OverCapacityAssign, OverCapacityLabel:
+	case OverCapacityEscalate:
+		if t.Backup == "" {
+			return fmt.Errorf("overCapacity %q requires backup to be set", t.OverCapacity)
+		}
+	default:
+		return fmt.Errorf("unknown overCapacity %q, expected %q, %q or %q", t.OverCapacity, OverCapacityAssign, OverCapacityEscalate, OverCapacityLabel)
+	}
+
+	return nil
+}
+
+// capacity returns the busyness at which the member is at capacity, 0 if unlimited.
+// As busyness is normalized by allocation, the limit applies to a full allocation.
+func (t TeamConfig) capacity(m MemberConfig) int {
+	if m.MaxOpenIssues > 0 {
+		return m.MaxOpenIssues
+	}
+	return t.MaxOpenIssues
 }
 
 type MemberConfig struct {
@@ -44,12 +191,35 @@ type MemberConfig struct {
 	IcalURL        string `yaml:"ical-url,omitempty"`
 	GoogleCalendar string `yaml:"googleCalendar,omitempty"`
 	Output         string `yaml:"output,omitempty"`
+
+	// IcalURLEnv is the name of an environment variable containing the ical-url, so that secret feed URLs don't need to
+	// be part of the config. See Config.ResolveCalendarSecrets.
+	IcalURLEnv string `yaml:"ical-url-env,omitempty"`
+
+	// MaxOpenIssues overrides the maxOpenIssues of the team for this member.
+	MaxOpenIssues int `yaml:"maxOpenIssues,omitempty"`
+
+	// Allocation is the share of time the member spends on the team, between 0 and 1. Defaults to 1.
+	// The busyness of a member is divided by their allocation.
+	Allocation float64 `yaml:"allocation,omitempty"`
 }
 
+// allocation returns the allocation of the member, defaulting to a full allocation.
+func (m MemberConfig) allocation() float64 {
+	if m.Allocation == 0 {
+		return 1
+	}
+	return m.Allocation
+}
+
+// ParseConfig parses and validates a config. Unknown fields are rejected, as they are usually typos which would be ignored silently.
 func ParseConfig(r io.Reader) (Config, error) {
 	var cfg Config
 
-	err := yaml.NewDecoder(r).Decode(&cfg)
+	dec := yaml.NewDecoder(r)
+	dec.SetStrict(true)
+
+	err := dec.Decode(&cfg)
 	if err != nil {
 		return cfg, fmt.Errorf("unable to parse config, due: %w", err)
 	}
@@ -59,10 +229,65 @@ func ParseConfig(r io.Reader) (Config, error) {
 		cfg.UnavailabilityLimit = calendar.DefaultUnavailabilityLimit
 	}
 
+	if err := cfg.Rules.Validate(); err != nil {
+		return cfg, pathError{path: []string{"rules"}, err: fmt.Errorf("unable to parse config, due: %w", err)}
+	}
+
+	if cfg.CodeOwners != nil {
+		for label := range cfg.CodeOwners.Labels {
+			if _, ok := cfg.Teams[codeOwnersTeamName(label)]; ok {
+				return cfg, pathError{
+					path: []string{"teams", codeOwnersTeamName(label)},
+					err:  fmt.Errorf("team %q conflicts with the team derived from CODEOWNERS for label %q", codeOwnersTeamName(label), label),
+				}
+			}
+		}
+	}
+
+	for name, t := range cfg.Teams {
+		if err := cfg.Rules.CheckRef(t.Rule); err != nil {
+			return cfg, pathError{path: []string{"teams", name, "rule"}, err: fmt.Errorf("invalid team %q: %w", name, err)}
+		}
+
+		if err := t.validate(); err != nil {
+			return cfg, pathError{path: []string{"teams", name}, err: fmt.Errorf("invalid team %q: %w", name, err)}
+		}
+	}
+
 	return cfg, nil
 }
 
+// pathError is an error of ParseConfig located at a path in the config, see Finding.Path.
+type pathError struct {
+	path []string
+	err  error
+}
+
+func (e pathError) Error() string {
+	return e.err.Error()
+}
+
+func (e pathError) Unwrap() error {
+	return e.err
+}
+
+// FetchConfig fetches the config at path and resolves its extends and include directives, see ResolveIncludes.
 func FetchConfig(ctx context.Context, client *github.Client, owner, repo, ref, path string) (io.Reader, error) {
+	raw, err := FetchFile(ctx, client, owner, repo, ref, path)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := ResolveIncludes(ctx, client, owner, repo, ref, path, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBuffer(merged), nil
+}
+
+// FetchFile fetches a file of a repository as it is, without resolving any directives.
+func FetchFile(ctx context.Context, client *github.Client, owner, repo, ref, path string) ([]byte, error) {
 	rawContent, _, _, err := client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{
 		Ref: ref,
 	})
@@ -76,5 +301,5 @@ func FetchConfig(ctx context.Context, client *github.Client, owner, repo, ref, p
 		return nil, fmt.Errorf("unable to load config, due %w", err)
 	}
 
-	return bytes.NewBuffer([]byte(content)), nil
+	return []byte(content), nil
 }
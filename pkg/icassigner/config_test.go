@@ -18,6 +18,7 @@ package icassigner
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -88,17 +89,22 @@ unavailabilityLimit: 6h` // redacted excerpt from a real world config
 	}
 
 	expectedRequiredLabels := []string{"cloud-prometheus", "enterprise-metrics"}
+	requiredLabels := team.RequireLabel.Labels()
 	for i, e := range expectedRequiredLabels {
-		if i >= len(team.RequireLabel) {
-			t.Error("Expected require label at index", i, "but only got", len(team.RequireLabel))
+		if i >= len(requiredLabels) {
+			t.Error("Expected require label at index", i, "but only got", len(requiredLabels))
 			continue
 		}
 
-		if e != team.RequireLabel[i] {
-			t.Error("Expected require label at index", i, "to be", e, ", but got:", team.RequireLabel[i])
+		if e != requiredLabels[i] {
+			t.Error("Expected require label at index", i, "to be", e, ", but got:", requiredLabels[i])
 		}
 	}
 
+	if len(team.RequireLabel.Any) != len(expectedRequiredLabels) {
+		t.Error("Expected legacy list of required labels to be parsed as any-of expression, but got", team.RequireLabel.String())
+	}
+
 	expectedMembers := []MemberConfig{
 		{
 			Name:    "tester1",
@@ -133,3 +139,16 @@ unavailabilityLimit: 6h` // redacted excerpt from a real world config
 	}
 
 }
+
+func TestParseConfig_RejectsNegativeMaxOpenIssues(t *testing.T) {
+	for name, raw := range map[string]string{
+		"team":   "teams: {loki: {requireLabel: [loki], maxOpenIssues: -1, members: [{name: alice}]}}",
+		"member": "teams: {loki: {requireLabel: [loki], members: [{name: alice, maxOpenIssues: -1}]}}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseConfig(strings.NewReader(raw)); err == nil || !strings.Contains(err.Error(), "maxOpenIssues") {
+				t.Errorf("expected negative maxOpenIssues to be rejected, got %v", err)
+			}
+		})
+	}
+}
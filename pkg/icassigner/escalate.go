@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+)
+
+const (
+	// EscalateReassign assigns unacknowledged issues to the next available member of the team.
+	EscalateReassign = "reassign"
+
+	// EscalateBackup mentions the backup of the team on unacknowledged issues.
+	EscalateBackup = "backup"
+
+	// EscalateBoth mentions the backup and assigns the next available member.
+	EscalateBoth = "both"
+
+	// DefaultLogin is the login of the GITHUB_TOKEN, which is used if the login of the token can't be determined.
+	DefaultLogin = "github-actions[bot]"
+
+	// escalationMarker is hidden in escalation comments to find out if an assignment has already been escalated.
+	escalationMarker = "<!-- ic-assignment:escalation -->"
+)
+
+// ackEvents are the timeline events by which assignees acknowledge an issue.
+var ackEvents = []string{"commented", "labeled", "unlabeled", "cross-referenced", "referenced", "closed"}
+
+// Escalate scans the open issues of the repository which have been assigned by this action and escalates those
+// whose assignee didn't acknowledge them within the ackTimeout of their team. Assignees acknowledge an issue
+// by commenting, changing its labels, referencing it or closing it.
+func (a *Action) Escalate(ctx context.Context, owner, repo string, now time.Time, dryRun bool) error {
+	issues, err := a.listAssignedIssues(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	level.Info(a.logger()).Log("msg", "checking open issues with assignees for escalation", "issues", len(issues))
+
+	// the outputs list all issues handled instead of only the last one
+	a.assignments = []Assignment{}
+	defer a.reportAll()
+
+	// a failing issue, e.g. due to missing permissions, must not stop the escalation of all others
+	var errs []error
+	for _, iss := range issues {
+		if err := a.escalateIssue(ctx, iss, now, dryRun); err != nil {
+			level.Error(a.logger()).Log("msg", "unable to escalate issue", "issue", iss.Number, "err", err)
+			errs = append(errs, fmt.Errorf("unable to escalate issue %d, due %w", iss.Number, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a *Action) escalateIssue(ctx context.Context, iss issue.Issue, now time.Time, dryRun bool) error {
+	if a.hasIgnoredLabel(iss) {
+		return nil
+	}
+
+	teamName, team, ok := escalationTeam(a.Config, iss, now)
+	if !ok {
+		return nil
+	}
+
+	assignees := assignedMembers(team.allMembers(), currentOwners(iss))
+	if len(assignees) == 0 {
+		return nil
+	}
+
+	timeline, err := a.listTimeline(ctx, iss)
+	if err != nil {
+		return err
+	}
+
+	login, err := a.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	assignedAt, ok := assignedByAt(timeline, assignees, login)
+	if !ok {
+		// only assignments of this action are escalated, everyone else knows what they are doing
+		return nil
+	}
+
+	if now.Sub(assignedAt) < team.AckTimeout || isAcknowledged(timeline, assignees, assignedAt) {
+		return nil
+	}
+
+	escalated, err := a.hasEscalationComment(ctx, iss, assignedAt)
+	if err != nil || escalated {
+		return err
+	}
+
+	level.Info(a.logger()).Log("msg", "issue hasn't been acknowledged in time, escalating", "issue", iss.Number, "assignees", strings.Join(assignees, ", "), "ackTimeout", team.AckTimeout, "escalation", team.escalation())
+
+	if dryRun {
+		level.Info(a.logger()).Log("msg", "not escalating because dry-run is enabled")
+		return nil
+	}
+
+	// the backup is mentioned before reassigning, so that the comment belongs to the previous assignment
+	if team.escalation() != EscalateReassign {
+		if err := a.mentionBackup(ctx, iss, team, assignees); err != nil {
+			return err
+		}
+	}
+
+	if team.escalation() == EscalateBackup {
+		return nil
+	}
+
+	chosen, err := a.assign(ctx, iss, team, teamName, assignees, assignees, false)
+	if errors.Is(err, errAtCapacity) {
+		return a.handleOverCapacity(ctx, iss, team, teamName, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	if chosen == "" && team.Backup != "" && team.escalation() == EscalateReassign {
+		// nobody is left to reassign to, so at least let the backup know
+		return a.mentionBackup(ctx, iss, team, assignees)
+	}
+
+	return nil
+}
+
+func (t TeamConfig) escalation() string {
+	if t.Escalation == "" {
+		return EscalateReassign
+	}
+	return t.Escalation
+}
+
+// escalationTeam returns the team responsible for the issue, merged like by Run if multiple teams match, if it has an
+// ackTimeout configured.
+func escalationTeam(cfg Config, iss issue.Issue, now time.Time) (string, TeamConfig, bool) {
+	team, name := findTeam(cfg, iss, now)
+	return name, team, name != "" && team.AckTimeout > 0
+}
+
+// assignedByAt returns when one of the assignees was assigned the last time, if that assignment was done by login.
+func assignedByAt(timeline []*github.Timeline, assignees []string, login string) (time.Time, bool) {
+	var assignedAt time.Time
+	var byLogin bool
+	for _, e := range timeline {
+		if e.GetEvent() != "assigned" || !containsFold(assignees, e.GetAssignee().GetLogin()) {
+			continue
+		}
+
+		if e.GetCreatedAt().After(assignedAt) {
+			assignedAt = e.GetCreatedAt()
+			byLogin = strings.EqualFold(e.GetActor().GetLogin(), login)
+		}
+	}
+
+	return assignedAt, byLogin
+}
+
+// login returns the login the action acts as, see Action.Login.
+func (a *Action) login(ctx context.Context) (string, error) {
+	if a.Login != "" {
+		return a.Login, nil
+	}
+
+	user, _, err := a.Client.Users.Get(ctx, "")
+	var errResp *github.ErrorResponse
+	switch {
+	case errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusForbidden:
+		// installation tokens like the GITHUB_TOKEN can't read the authenticated user
+		a.Login = DefaultLogin
+	case err != nil:
+		return "", fmt.Errorf("unable to get the authenticated user, due %w", err)
+	default:
+		a.Login = user.GetLogin()
+	}
+
+	return a.Login, nil
+}
+
+// isAcknowledged reports whether one of the assignees acted on the issue after it was assigned.
+func isAcknowledged(timeline []*github.Timeline, assignees []string, assignedAt time.Time) bool {
+	for _, e := range timeline {
+		if e.GetCreatedAt().Before(assignedAt) || !containsFold(ackEvents, e.GetEvent()) {
+			continue
+		}
+
+		if containsFold(assignees, e.GetActor().GetLogin()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Action) mentionBackup(ctx context.Context, iss issue.Issue, team TeamConfig, assignees []string) error {
+	return a.comment(ctx, iss, fmt.Sprintf("%s\n@%s this issue hasn't been acknowledged by @%s within %v.", escalationMarker, strings.TrimPrefix(team.Backup, "@"), strings.Join(assignees, ", @"), team.AckTimeout))
+}
+
+func (a *Action) comment(ctx context.Context, iss issue.Issue, body string) error {
+	_, _, err := a.Client.Issues.CreateComment(ctx, iss.Owner, iss.Repo, iss.Number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return fmt.Errorf("unable to comment, due %w", err)
+	}
+	return nil
+}
+
+func (a *Action) hasEscalationComment(ctx context.Context, iss issue.Issue, since time.Time) (bool, error) {
+	opts := &github.IssueListCommentsOptions{Since: since, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := a.Client.Issues.ListComments(ctx, iss.Owner, iss.Repo, iss.Number, opts)
+		if err != nil {
+			return false, fmt.Errorf("unable to list comments, due %w", err)
+		}
+
+		for _, c := range comments {
+			if c.GetCreatedAt().After(since) && strings.Contains(c.GetBody(), escalationMarker) {
+				return true, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (a *Action) listTimeline(ctx context.Context, iss issue.Issue) ([]*github.Timeline, error) {
+	opts := &github.ListOptions{PerPage: 100}
+
+	var result []*github.Timeline
+	for {
+		events, resp, err := a.Client.Issues.ListIssueTimeline(ctx, iss.Owner, iss.Repo, iss.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list timeline, due %w", err)
+		}
+
+		result = append(result, events...)
+
+		if resp.NextPage == 0 {
+			return result, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (a *Action) listAssignedIssues(ctx context.Context, owner, repo string) ([]issue.Issue, error) {
+	opts := &github.IssueListByRepoOptions{State: "open", Assignee: "*", ListOptions: github.ListOptions{PerPage: 100}}
+
+	var result []issue.Issue
+	for {
+		issues, resp, err := a.Client.Issues.ListByRepo(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list open issues, due %w", err)
+		}
+
+		for _, i := range issues {
+			// pull requests are listed as issues as well, but only issues are escalated and rebalanced
+			if i.IsPullRequest() {
+				continue
+			}
+
+			iss := issue.FromGithub(i)
+			iss.Owner, iss.Repo = owner, repo
+			result = append(result, iss)
+		}
+
+		if resp.NextPage == 0 {
+			return result, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+)
+
+func timelineEvent(event, actor, actorType, assignee string, at time.Time) *github.Timeline {
+	e := &github.Timeline{
+		Event:     github.String(event),
+		Actor:     &github.User{Login: github.String(actor), Type: github.String(actorType)},
+		CreatedAt: &at,
+	}
+	if assignee != "" {
+		e.Assignee = &github.User{Login: github.String(assignee)}
+	}
+	return e
+}
+
+func TestAssignedByAt(t *testing.T) {
+	t0 := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	timeline := []*github.Timeline{
+		timelineEvent("assigned", "github-actions[bot]", "Bot", "alice", t0),
+		timelineEvent("assigned", "bob", "User", "alice", t0.Add(time.Hour)),
+	}
+	if _, ok := assignedByAt(timeline, []string{"alice"}, DefaultLogin); ok {
+		t.Error("expected the latest assignment by a user to take precedence")
+	}
+
+	timeline = []*github.Timeline{
+		timelineEvent("assigned", "github-actions[bot]", "Bot", "alice", t0),
+		timelineEvent("assigned", "github-actions[bot]", "Bot", "carol", t0.Add(time.Hour)),
+	}
+	assignedAt, ok := assignedByAt(timeline, []string{"Alice"}, DefaultLogin)
+	if !ok || !assignedAt.Equal(t0) {
+		t.Errorf("expected alice to be assigned by the action at %v, got %v %v", t0, assignedAt, ok)
+	}
+
+	// other bots don't assign on behalf of the action
+	timeline = []*github.Timeline{timelineEvent("assigned", "renovate[bot]", "Bot", "alice", t0)}
+	if _, ok := assignedByAt(timeline, []string{"alice"}, DefaultLogin); ok {
+		t.Error("expected assignments of other bots to be ignored")
+	}
+
+	// the action can act as a user when it runs with a personal access token
+	timeline = []*github.Timeline{timelineEvent("assigned", "assign-bot", "User", "alice", t0)}
+	if _, ok := assignedByAt(timeline, []string{"alice"}, "assign-bot"); !ok {
+		t.Error("expected the assignment by the user of the token to be found")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"login": "assign-bot"}`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	a := &Action{Client: gh}
+	if login, err := a.login(context.Background()); err != nil || login != "assign-bot" {
+		t.Errorf("expected the login of the token, got %q %v", login, err)
+	}
+
+	// installation tokens can't read the authenticated user
+	status = http.StatusForbidden
+	a = &Action{Client: gh}
+	if login, err := a.login(context.Background()); err != nil || login != DefaultLogin {
+		t.Errorf("expected the default login, got %q %v", login, err)
+	}
+}
+
+func TestEscalate_ContinuesAfterFailingIssue(t *testing.T) {
+	now := time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC)
+	assignedAt := now.Add(-25 * time.Hour)
+
+	var comments []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/issues":
+			w.Write([]byte(`[
+				{"number": 1, "labels": [{"name": "loki"}], "assignees": [{"login": "alice"}]},
+				{"number": 2, "labels": [{"name": "loki"}], "assignees": [{"login": "alice"}], "pull_request": {"url": "https://example.com"}},
+				{"number": 3, "labels": [{"name": "loki"}], "assignees": [{"login": "alice"}]}
+			]`))
+		case r.URL.Path == "/repos/owner/repo/issues/1/timeline":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+		case r.URL.Path == "/repos/owner/repo/issues/3/timeline":
+			json.NewEncoder(w).Encode([]*github.Timeline{timelineEvent("assigned", DefaultLogin, "Bot", "alice", assignedAt)})
+		case r.URL.Path == "/repos/owner/repo/issues/3/comments" && r.Method == http.MethodGet:
+			w.Write([]byte(`[]`))
+		case r.URL.Path == "/repos/owner/repo/issues/3/comments" && r.Method == http.MethodPost:
+			var c github.IssueComment
+			json.NewDecoder(r.Body).Decode(&c)
+			comments = append(comments, c.GetBody())
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	a := &Action{Client: gh, Login: DefaultLogin, Config: Config{Teams: map[string]TeamConfig{
+		"loki": {
+			RequireLabel: labelexpr.Label("loki"),
+			Members:      []MemberConfig{{Name: "alice"}},
+			AckTimeout:   24 * time.Hour,
+			Backup:       "@grafana/loki-leads",
+			Escalation:   EscalateBackup,
+		},
+	}}}
+
+	err := a.Escalate(context.Background(), "owner", "repo", now, false)
+	if err == nil || !strings.Contains(err.Error(), "issue 1") {
+		t.Errorf("expected the error of issue 1 to be returned, got %v", err)
+	}
+	if len(comments) != 1 {
+		t.Errorf("expected issue 3 to be escalated despite the failure of issue 1, got %q", comments)
+	}
+}
+
+func TestIsAcknowledged(t *testing.T) {
+	t0 := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		event    *github.Timeline
+		expected bool
+	}{
+		{name: "comment of assignee", event: timelineEvent("commented", "alice", "User", "", t0.Add(time.Hour)), expected: true},
+		{name: "label change of assignee", event: timelineEvent("labeled", "alice", "User", "", t0.Add(time.Hour)), expected: true},
+		{name: "reference by assignee", event: timelineEvent("cross-referenced", "alice", "User", "", t0.Add(time.Hour)), expected: true},
+		{name: "comment of someone else", event: timelineEvent("commented", "bob", "User", "", t0.Add(time.Hour)), expected: false},
+		{name: "comment before assignment", event: timelineEvent("commented", "alice", "User", "", t0.Add(-time.Hour)), expected: false},
+		{name: "subscription of assignee", event: timelineEvent("subscribed", "alice", "User", "", t0.Add(time.Hour)), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := isAcknowledged([]*github.Timeline{tc.event}, []string{"alice"}, t0); result != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Escalation(t *testing.T) {
+	cfg, err := ParseConfig(strings.NewReader(`
+teams:
+  loki:
+    requireLabel: [loki]
+    ackTimeout: 24h
+    backup: grafana/loki-leads
+    escalation: both
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Teams["loki"].AckTimeout != 24*time.Hour {
+		t.Errorf("expected ackTimeout of 24h, got %v", cfg.Teams["loki"].AckTimeout)
+	}
+
+	_, err = ParseConfig(strings.NewReader(`
+teams:
+  loki:
+    requireLabel: [loki]
+    ackTimeout: 24h
+    escalation: backup
+`))
+	if err == nil {
+		t.Error("expected escalation to the backup without backup to be rejected")
+	}
+}
+
+func TestEscalateIssue_MentionsBackup(t *testing.T) {
+	now := time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC)
+	assignedAt := now.Add(-25 * time.Hour)
+
+	var comments []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/issues/1/timeline":
+			json.NewEncoder(w).Encode([]*github.Timeline{
+				timelineEvent("assigned", "github-actions[bot]", "Bot", "alice", assignedAt),
+				timelineEvent("commented", "bob", "User", "", assignedAt.Add(time.Hour)),
+			})
+		case r.URL.Path == "/repos/owner/repo/issues/1/comments" && r.Method == http.MethodGet:
+			w.Write([]byte(`[]`))
+		case r.URL.Path == "/repos/owner/repo/issues/1/comments" && r.Method == http.MethodPost:
+			var c github.IssueComment
+			json.NewDecoder(r.Body).Decode(&c)
+			comments = append(comments, c.GetBody())
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	a := &Action{Client: gh, Login: DefaultLogin, Config: Config{Teams: map[string]TeamConfig{
+		"loki": {
+			RequireLabel: labelexpr.Label("loki"),
+			Members:      []MemberConfig{{Name: "alice"}},
+			AckTimeout:   24 * time.Hour,
+			Backup:       "@grafana/loki-leads",
+			Escalation:   EscalateBackup,
+		},
+	}}}
+
+	iss := issue.Issue{Owner: "owner", Repo: "repo", Number: 1, Labels: []string{"loki"}, Assignees: []string{"alice"}}
+	if err := a.escalateIssue(context.Background(), iss, now, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(comments) != 1 || !strings.Contains(comments[0], "@grafana/loki-leads this issue hasn't been acknowledged by @alice within 24h0m0s.") {
+		t.Errorf("expected the backup to be mentioned, got %q", comments)
+	}
+
+	// within the ackTimeout nothing happens
+	comments = nil
+	if err := a.escalateIssue(context.Background(), iss, assignedAt.Add(time.Hour), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(comments) != 0 {
+		t.Errorf("expected no escalation within the ackTimeout, got %q", comments)
+	}
+}
+
+func TestEscalateIssue_AtCapacity(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+	t.Setenv("GITHUB_OUTPUT", filepath.Join(t.TempDir(), "output"))
+
+	now := time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/issues/1/timeline":
+			json.NewEncoder(w).Encode([]*github.Timeline{
+				timelineEvent("assigned", "github-actions[bot]", "Bot", "alice", now.Add(-25*time.Hour)),
+			})
+		case r.URL.Path == "/repos/owner/repo/issues/1/comments" && r.Method == http.MethodGet:
+			w.Write([]byte(`[]`))
+		case r.URL.Path == "/repos/owner/repo/issues" && r.URL.Query().Get("assignee") == "bob":
+			w.Write([]byte(`[{"number": 12, "state": "open"}]`))
+		case r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			requests = append(requests, r.URL.Path+" "+strings.TrimSpace(string(body)))
+			w.Write([]byte(`[]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	a := &Action{Client: gh, Login: DefaultLogin, Config: Config{Teams: map[string]TeamConfig{
+		"loki": {
+			RequireLabel:  labelexpr.Label("loki"),
+			Members:       []MemberConfig{{Name: "alice"}, {Name: "bob"}},
+			AckTimeout:    24 * time.Hour,
+			MaxOpenIssues: 1,
+			OverCapacity:  OverCapacityLabel,
+		},
+	}}}
+
+	// bob is the only one left to reassign to, but at capacity
+	iss := issue.Issue{Owner: "owner", Repo: "repo", Number: 1, Labels: []string{"loki"}, Assignees: []string{"alice"}}
+	if err := a.escalateIssue(context.Background(), iss, now, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requests) != 1 || requests[0] != `/repos/owner/repo/issues/1/labels ["needs-owner"]` {
+		t.Errorf("expected the overCapacity policy of the team to be applied, got %q", requests)
+	}
+}
+
+func TestEscalationTeam_MergesTeams(t *testing.T) {
+	cfg := Config{Teams: map[string]TeamConfig{
+		"loki": {
+			RequireLabel: labelexpr.Label("loki"),
+			Members:      []MemberConfig{{Name: "alice"}},
+		},
+		"mimir": {
+			RequireLabel: labelexpr.Label("mimir"),
+			Members:      []MemberConfig{{Name: "bob"}},
+			AckTimeout:   time.Hour,
+			Backup:       "@grafana/mimir-leads",
+		},
+	}}
+
+	name, team, ok := escalationTeam(cfg, issue.Issue{Labels: []string{"loki", "mimir"}}, time.Now())
+	if !ok || name != "Merged (loki, mimir)" {
+		t.Fatalf("expected the merged team to be escalated, got %q %v", name, ok)
+	}
+	if names := strings.Join(memberNames(team.allMembers()), ","); names != "alice,bob" {
+		t.Errorf("expected the members of both teams like on assignment, got %v", names)
+	}
+	if team.AckTimeout != time.Hour || team.Backup != "@grafana/mimir-leads" {
+		t.Errorf("expected the escalation settings of mimir, got %+v", team)
+	}
+
+	if _, _, ok := escalationTeam(cfg, issue.Issue{Labels: []string{"loki"}}, time.Now()); ok {
+		t.Error("expected teams without ackTimeout not to be escalated")
+	}
+}
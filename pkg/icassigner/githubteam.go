@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// ResolveGithubTeams adds the members of the GitHub team of every team with githubTeam to its members. Members which are
+// already configured explicitly (in any tier) keep their configuration, all others are added to the primary tier.
+// Members in the exclude list of a team are removed afterwards.
+func (c *Config) ResolveGithubTeams(ctx context.Context, client *github.Client) error {
+	for name, t := range c.Teams {
+		if t.GithubTeam != "" {
+			org, slug, _ := strings.Cut(t.GithubTeam, "/")
+
+			logins, err := listGithubTeamMembers(ctx, client, org, slug)
+			var errResp *github.ErrorResponse
+			if errors.As(err, &errResp) && (errResp.Response.StatusCode == http.StatusForbidden || errResp.Response.StatusCode == http.StatusNotFound) {
+				// GitHub hides teams from tokens without access, the default GITHUB_TOKEN never has it
+				return fmt.Errorf("unable to resolve github team %q of team %q, the gh-token needs read:org access to the organization, due %w", t.GithubTeam, name, err)
+			}
+			if err != nil {
+				return fmt.Errorf("unable to resolve github team %q of team %q, due %w", t.GithubTeam, name, err)
+			}
+
+			explicit := t.allMembers()
+			for _, login := range logins {
+				if !isMember(explicit, login) {
+					t.Members = append(t.Members, MemberConfig{Name: login})
+				}
+			}
+		}
+
+		for _, excluded := range t.Exclude {
+			t.Members = withoutMember(t.Members, excluded)
+			t.Secondary = withoutMember(t.Secondary, excluded)
+			t.Manager = withoutMember(t.Manager, excluded)
+		}
+
+		c.Teams[name] = t
+	}
+
+	return nil
+}
+
+// listGithubTeamMembers returns the logins of all members of a team, following pagination.
+// go-github doesn't support to look up teams by slug, so the request is built manually.
+func listGithubTeamMembers(ctx context.Context, client *github.Client, org, slug string) ([]string, error) {
+	var result []string
+	for page := 1; page != 0; {
+		req, err := client.NewRequest("GET", fmt.Sprintf("orgs/%s/teams/%s/members?per_page=100&page=%d", org, slug, page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var users []*github.User
+		resp, err := client.Do(ctx, req, &users)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, u := range users {
+			result = append(result, u.GetLogin())
+		}
+
+		page = resp.NextPage
+	}
+
+	return result, nil
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestResolveGithubTeams(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/grafana/teams/loki-squad/members" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"login": "carol"}, {"login": "dave"}]`))
+			return
+		}
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/grafana/teams/loki-squad/members?per_page=100&page=2>; rel="next"`, server.URL))
+		w.Write([]byte(`[{"login": "alice"}, {"login": "bob"}]`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	cfg, err := ParseConfig(strings.NewReader(`
+teams:
+  loki:
+    requireLabel: [loki]
+    githubTeam: grafana/loki-squad
+    exclude: [dave]
+    members:
+      - name: alice
+        ical-url: https://example.com/alice.ics
+    secondary:
+      - name: bob
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cfg.ResolveGithubTeams(context.Background(), gh); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	team := cfg.Teams["loki"]
+	if names := strings.Join(memberNames(team.Members), ","); names != "alice,carol" {
+		t.Errorf("expected members alice,carol, got %v", names)
+	}
+	if team.Members[0].IcalURL != "https://example.com/alice.ics" {
+		t.Errorf("expected explicit configuration of alice to be kept, got %v", team.Members[0])
+	}
+	if names := strings.Join(memberNames(team.Secondary), ","); names != "bob" {
+		t.Errorf("expected bob to stay in the secondary tier, got %v", names)
+	}
+}
+
+func TestResolveGithubTeams_Pagination(t *testing.T) {
+	var pages []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+
+		// the last page doesn't link to a next one
+		switch page {
+		case "1":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/grafana/teams/loki-squad/members?per_page=100&page=2>; rel="next", <%s/orgs/grafana/teams/loki-squad/members?per_page=100&page=3>; rel="last"`, server.URL, server.URL))
+			w.Write([]byte(`[{"login": "alice"}]`))
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/grafana/teams/loki-squad/members?per_page=100&page=3>; rel="next", <%s/orgs/grafana/teams/loki-squad/members?per_page=100&page=1>; rel="first"`, server.URL, server.URL))
+			w.Write([]byte(`[{"login": "bob"}]`))
+		case "3":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/grafana/teams/loki-squad/members?per_page=100&page=2>; rel="prev"`, server.URL))
+			w.Write([]byte(`[{"login": "carol"}]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	cfg := Config{Teams: map[string]TeamConfig{"loki": {GithubTeam: "grafana/loki-squad"}}}
+	if err := cfg.ResolveGithubTeams(context.Background(), gh); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if names := strings.Join(memberNames(cfg.Teams["loki"].Members), ","); names != "alice,bob,carol" {
+		t.Errorf("expected members of all pages, got %v", names)
+	}
+	if strings.Join(pages, ",") != "1,2,3" {
+		t.Errorf("expected every page to be requested once, got %v", pages)
+	}
+}
+
+func TestResolveGithubTeams_WithoutAccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Not Found"}`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	cfg := Config{Teams: map[string]TeamConfig{"loki": {GithubTeam: "grafana/loki-squad"}}}
+	err := cfg.ResolveGithubTeams(context.Background(), gh)
+	if err == nil || !strings.Contains(err.Error(), "needs read:org access") {
+		t.Errorf("expected error to mention the required access, got %v", err)
+	}
+}
+
+func TestParseConfig_InvalidGithubTeam(t *testing.T) {
+	_, err := ParseConfig(strings.NewReader(`
+teams:
+  loki:
+    requireLabel: [loki]
+    githubTeam: loki-squad
+`))
+	if err == nil {
+		t.Error("expected githubTeam without org to be rejected")
+	}
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	directiveExtends = "extends"
+	directiveInclude = "include"
+
+	// maxIncludeDepth limits how deep includes can be nested.
+	maxIncludeDepth = 10
+)
+
+// configRef references a config file, written as `[owner/repo:]path[@ref]`.
+type configRef struct {
+	owner, repo, path, ref string
+}
+
+func (r configRef) String() string {
+	return fmt.Sprintf("%s/%s:%s@%s", r.owner, r.repo, r.path, r.ref)
+}
+
+// parseConfigRef parses a reference of a config file. References without repository point to the repository and ref
+// of the config containing them. References to other repositories without ref point to their default branch.
+func parseConfigRef(s string, parent configRef) (configRef, error) {
+	ref := configRef{owner: parent.owner, repo: parent.repo, ref: parent.ref}
+
+	p := s
+	if repo, rest, ok := strings.Cut(s, ":"); ok {
+		owner, name, ok := strings.Cut(repo, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return ref, fmt.Errorf("invalid repository %q in %q, expected owner/repo", repo, s)
+		}
+		ref.owner, ref.repo, ref.ref = owner, name, ""
+		p = rest
+	}
+
+	if at := strings.LastIndex(p, "@"); at >= 0 {
+		ref.ref = p[at+1:]
+		p = p[:at]
+		if ref.ref == "" {
+			return ref, fmt.Errorf("empty ref in %q", s)
+		}
+	}
+
+	ref.path = path.Clean(strings.TrimPrefix(p, "/"))
+	if p == "" || ref.path == "." {
+		return ref, fmt.Errorf("missing path in %q", s)
+	}
+	return ref, nil
+}
+
+// ResolveIncludes resolves the extends and include directives of the raw config at path and returns the merged config.
+//
+// Both directives reference other configs as `[owner/repo:]path[@ref]`, e.g. `grafana/.github:assignment/teams.yaml@v1`.
+// The config referenced by extends is the base, the configs referenced by include are merged on top of it in order and
+// the config itself is merged last. Mappings are merged key by key, all other values (including lists) are replaced by
+// the later config. A key set to null removes the value of the earlier configs. Referenced configs can use directives
+// themselves, paths without repository are resolved in their repository and ref.
+func ResolveIncludes(ctx context.Context, client *github.Client, owner, repo, ref, path string, raw []byte) ([]byte, error) {
+	merged, err := resolveIncludes(ctx, client, configRef{owner: owner, repo: repo, path: path, ref: ref}, raw, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(merged)
+}
+
+// IncludedFiles returns the paths of all configs in the same repository and ref, which the raw config at path references
+// directly or through other configs, e.g. to check them along with the config.
+func IncludedFiles(ctx context.Context, client *github.Client, owner, repo, ref, path string, raw []byte) ([]string, error) {
+	var files []string
+	_, err := resolveIncludes(ctx, client, configRef{owner: owner, repo: repo, path: path, ref: ref}, raw, nil, func(included configRef) {
+		if included.owner == owner && included.repo == repo && included.ref == ref && !slices.Contains(files, included.path) {
+			files = append(files, included.path)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// resolveIncludes merges the configs referenced by raw, calling included (if set) for every referenced config.
+func resolveIncludes(ctx context.Context, client *github.Client, self configRef, raw []byte, stack []string, included func(configRef)) (map[interface{}]interface{}, error) {
+	for _, s := range stack {
+		if s == self.String() {
+			return nil, fmt.Errorf("config %s includes itself", self)
+		}
+	}
+	stack = append(stack, self.String())
+	if len(stack) > maxIncludeDepth {
+		return nil, fmt.Errorf("includes of %s are nested deeper than %d levels", stack[0], maxIncludeDepth)
+	}
+
+	var cfg map[interface{}]interface{}
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse %s, due %w", self, err)
+	}
+
+	var refs []string
+	if extends, ok := cfg[directiveExtends]; ok {
+		s, ok := extends.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s of %s must be a single reference", directiveExtends, self)
+		}
+		refs = append(refs, s)
+	}
+
+	if include, ok := cfg[directiveInclude]; ok {
+		list, ok := include.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s of %s must be a list of references", directiveInclude, self)
+		}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s of %s must be a list of references", directiveInclude, self)
+			}
+			refs = append(refs, s)
+		}
+	}
+
+	delete(cfg, directiveExtends)
+	delete(cfg, directiveInclude)
+
+	result := map[interface{}]interface{}{}
+	for _, s := range refs {
+		ref, err := parseConfigRef(s, self)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference in %s, due %w", self, err)
+		}
+
+		if included != nil {
+			included(ref)
+		}
+
+		content, err := FetchFile(ctx, client, ref.owner, ref.repo, ref.ref, ref.path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to include %s, due %w", ref, err)
+		}
+
+		resolved, err := resolveIncludes(ctx, client, ref, content, stack, included)
+		if err != nil {
+			return nil, err
+		}
+
+		mergeConfig(result, resolved)
+	}
+
+	mergeConfig(result, cfg)
+	return result, nil
+}
+
+// mergeConfig merges src into dst. Mappings are merged recursively, all other values are replaced and null values remove keys.
+func mergeConfig(dst, src map[interface{}]interface{}) {
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+
+		srcMap, srcIsMap := v.(map[interface{}]interface{})
+		dstMap, dstIsMap := dst[k].(map[interface{}]interface{})
+		if srcIsMap && dstIsMap {
+			mergeConfig(dstMap, srcMap)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// newContentsServer serves the given files by "owner/repo:path@ref" via the contents API.
+func newContentsServer(t *testing.T, files map[string]string) *github.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := strings.CutPrefix(r.URL.Path, "/repos/")
+		if !ok {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+
+		parts := strings.SplitN(p, "/", 4)
+		key := fmt.Sprintf("%s/%s:%s@%s", parts[0], parts[1], parts[3], r.URL.Query().Get("ref"))
+		content, ok := files[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+
+		fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, base64.StdEncoding.EncodeToString([]byte(content)))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+	return gh
+}
+
+func TestFetchConfig_Includes(t *testing.T) {
+	gh := newContentsServer(t, map[string]string{
+		"owner/repo:.github/assignment.yaml@sha": `
+extends: grafana/.github:assignment/base.yaml@v1
+include:
+  - .github/mimir.yaml
+teams:
+  loki:
+    members:
+      - name: carol
+  tempo: null
+ignoreLabels: [wontfix]
+`,
+		"owner/repo:.github/mimir.yaml@sha": `
+teams:
+  mimir:
+    requireLabel: [mimir]
+    members:
+      - name: dave
+`,
+		"grafana/.github:assignment/base.yaml@v1": `
+include: [assignment/teams.yaml]
+unavailabilityLimit: 12h
+ignoreLabels: [stale]
+`,
+		"grafana/.github:assignment/teams.yaml@v1": `
+teams:
+  loki:
+    requireLabel: [loki]
+    maxOpenIssues: 5
+    members:
+      - name: alice
+      - name: bob
+  tempo:
+    requireLabel: [tempo]
+    members:
+      - name: erin
+`,
+	})
+
+	r, err := FetchConfig(context.Background(), gh, "owner", "repo", "sha", ".github/assignment.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := ParseConfig(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.UnavailabilityLimit.String() != "12h0m0s" {
+		t.Errorf("expected unavailabilityLimit of the base config, got %v", cfg.UnavailabilityLimit)
+	}
+	if strings.Join(cfg.IgnoredLabels, ",") != "wontfix" {
+		t.Errorf("expected lists to be replaced, got %v", cfg.IgnoredLabels)
+	}
+	if _, ok := cfg.Teams["tempo"]; ok {
+		t.Error("expected team tempo to be removed by null")
+	}
+
+	loki := cfg.Teams["loki"]
+	if loki.MaxOpenIssues != 5 || !loki.RequireLabel.Match([]string{"loki"}) {
+		t.Errorf("expected team loki to keep the settings of the base config, got %+v", loki)
+	}
+	if names := strings.Join(memberNames(loki.Members), ","); names != "carol" {
+		t.Errorf("expected members of loki to be replaced, got %v", names)
+	}
+
+	if mimir := cfg.Teams["mimir"]; !mimir.RequireLabel.Match([]string{"mimir"}) || len(mimir.Members) != 1 {
+		t.Errorf("expected team mimir to be included, got %+v", mimir)
+	}
+}
+
+func TestIncludedFiles(t *testing.T) {
+	raw := `
+extends: grafana/.github:assignment/base.yaml@v1
+include:
+  - .github/mimir.yaml
+  - .github/loki.yaml@main
+`
+	gh := newContentsServer(t, map[string]string{
+		"owner/repo:.github/mimir.yaml@sha":         "include: [.github/members/mimir.yaml]",
+		"owner/repo:.github/members/mimir.yaml@sha": "teams: {}",
+		"owner/repo:.github/loki.yaml@main":         "teams: {}",
+		"grafana/.github:assignment/base.yaml@v1":   "teams: {}",
+	})
+
+	files, err := IncludedFiles(context.Background(), gh, "owner", "repo", "sha", ".github/assignment.yaml", []byte(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// configs of other repositories or refs aren't changed along with the config
+	expected := []string{".github/mimir.yaml", ".github/members/mimir.yaml"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected included files %v, got %v", expected, files)
+	}
+}
+
+func TestFetchConfig_IncludeCycle(t *testing.T) {
+	gh := newContentsServer(t, map[string]string{
+		"owner/repo:a.yaml@sha": "include: [b.yaml]",
+		"owner/repo:b.yaml@sha": "include: [a.yaml]",
+	})
+
+	_, err := FetchConfig(context.Background(), gh, "owner", "repo", "sha", "a.yaml")
+	if err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("expected cycle to be detected, got %v", err)
+	}
+}
+
+func TestParseConfigRef(t *testing.T) {
+	parent := configRef{owner: "owner", repo: "repo", path: ".github/assignment.yaml", ref: "sha"}
+
+	for s, expected := range map[string]configRef{
+		"teams.yaml":                           {owner: "owner", repo: "repo", path: "teams.yaml", ref: "sha"},
+		"/teams.yaml@main":                     {owner: "owner", repo: "repo", path: "teams.yaml", ref: "main"},
+		"grafana/.github:assignment/base.yaml": {owner: "grafana", repo: ".github", path: "assignment/base.yaml"},
+		"grafana/.github:base.yaml@v1":         {owner: "grafana", repo: ".github", path: "base.yaml", ref: "v1"},
+	} {
+		ref, err := parseConfigRef(s, parent)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", s, err)
+		}
+		if ref != expected {
+			t.Errorf("expected %q to be parsed as %+v, got %+v", s, expected, ref)
+		}
+	}
+
+	for _, s := range []string{"", "grafana:base.yaml", "base.yaml@", "grafana/.github:"} {
+		if _, err := parseConfigRef(s, parent); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestCheckConfig_Includes(t *testing.T) {
+	gh := newContentsServer(t, map[string]string{
+		"owner/repo:teams.yaml@sha": "teams: {loki: {requireLabel: [loki], escalation: backup}}",
+	})
+
+	raw := []byte("include:\n  - teams.yaml\n  - missing.yaml\n")
+	result, err := CheckConfig(context.Background(), gh, "owner", "repo", "sha", ".github/assignment.yaml", raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Line != 1 {
+		t.Errorf("expected missing include to be reported at the include directive, got %+v", result.Errors)
+	}
+
+	raw = []byte("include: [teams.yaml]\nteams:\n  loki:\n    ackTimeout: 1h\n")
+	result, err = CheckConfig(context.Background(), gh, "owner", "repo", "sha", ".github/assignment.yaml", raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Line != 3 {
+		t.Errorf("expected invalid included team to be reported at the team, got %+v", result.Errors)
+	}
+}
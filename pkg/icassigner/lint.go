@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+)
+
+// Finding is a problem found in a config.
+type Finding struct {
+	// Path locates the finding in the config by the keys and indexes leading to it, e.g. ["teams", "loki", "members", "1"].
+	Path []string
+
+	// Line is the line of the finding in the raw config, 0 if unknown. It's only set by CheckConfig.
+	Line int
+
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Message
+}
+
+// Lint reports parts of a valid config which most likely don't work as intended, e.g. teams without members.
+// Teams derived at run time, see ResolveGithubTeams and ResolveCodeOwners, are not known yet. The findings are sorted.
+func (c *Config) Lint() []Finding {
+	var findings []Finding
+
+	names := make([]string, 0, len(c.Teams))
+	for name := range c.Teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	referenced := map[string]bool{}
+	withoutCalendar := map[string]bool{}
+	for _, name := range names {
+		t := c.Teams[name]
+		referenced[t.Rule] = true
+
+		if len(t.allMembers()) == 0 && t.GithubTeam == "" {
+			findings = append(findings, Finding{
+				Path:    []string{"teams", name},
+				Message: fmt.Sprintf("team %q has no members", name),
+			})
+		}
+
+		if t.RequireLabel.IsZero() && t.Rule == "" {
+			findings = append(findings, Finding{
+				Path:    []string{"teams", name},
+				Message: fmt.Sprintf("team %q never matches, as neither requireLabel nor rule is set", name),
+			})
+		}
+
+		seen := map[string]bool{}
+		for _, tier := range t.tiers() {
+			for idx, m := range tier.members {
+				path := []string{"teams", name, tier.key, strconv.Itoa(idx)}
+
+				login := strings.ToLower(m.Name)
+				if seen[login] {
+					findings = append(findings, Finding{
+						Path:    path,
+						Message: fmt.Sprintf("member %q is listed more than once in team %q", m.Name, name),
+					})
+					continue
+				}
+				seen[login] = true
+
+				if m.IcalURL == "" && m.IcalURLEnv == "" && m.GoogleCalendar == "" && !withoutCalendar[login] {
+					withoutCalendar[login] = true
+					findings = append(findings, Finding{
+						Path:    path,
+						Message: fmt.Sprintf("member %q has no calendar and is always considered available, unless the ical-urls input contains it", m.Name),
+					})
+				}
+			}
+		}
+	}
+
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if labels, ok := overlap(c.Teams[a], c.Teams[b]); ok {
+				findings = append(findings, Finding{
+					Path:    []string{"teams", b, "requireLabel"},
+					Message: fmt.Sprintf("teams %q and %q overlap, issues labeled %s are assigned to members of both", a, b, strings.Join(labels, ", ")),
+				})
+			}
+		}
+	}
+
+	for name := range c.Rules {
+		if !referenced[name] {
+			findings = append(findings, Finding{
+				Path:    []string{"rules", name},
+				Message: fmt.Sprintf("rule %q is never referenced", name),
+			})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Message < findings[j].Message })
+	return findings
+}
+
+// overlap reports whether there are labels which match the label expressions of both teams, and returns an example.
+// Teams with rules are skipped, as rules depend on more than labels.
+func overlap(a, b TeamConfig) ([]string, bool) {
+	if a.Rule != "" || b.Rule != "" || a.RequireLabel.IsZero() || b.RequireLabel.IsZero() {
+		return nil, false
+	}
+
+	// try the smallest examples first
+	var candidates [][]string
+	for _, sa := range labelSets(a.RequireLabel) {
+		for _, sb := range labelSets(b.RequireLabel) {
+			labels := append(slices.Clone(sa), sb...)
+			sort.Strings(labels)
+			candidates = append(candidates, slices.Compact(labels))
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i]) < len(candidates[j]) })
+
+	for _, labels := range candidates {
+		if a.RequireLabel.Match(labels) && b.RequireLabel.Match(labels) {
+			return labels, true
+		}
+	}
+	return nil, false
+}
+
+// maxLabelSets limits the amount of label sets considered per expression
+const maxLabelSets = 64
+
+// labelSets returns sets of labels likely matching the expression, one for every alternative of its any expressions.
+// Negated labels are never part of the sets.
+func labelSets(e labelexpr.Expr) [][]string {
+	if e.Label != "" {
+		return [][]string{{e.Label}}
+	}
+
+	sets := [][]string{nil}
+	for _, sub := range e.All {
+		sets = product(sets, labelSets(sub))
+	}
+
+	if len(e.Any) > 0 {
+		var alternatives [][]string
+		for _, sub := range e.Any {
+			alternatives = append(alternatives, labelSets(sub)...)
+		}
+		sets = product(sets, alternatives)
+	}
+
+	return sets
+}
+
+// product combines every set of a with every set of b.
+func product(a, b [][]string) [][]string {
+	var result [][]string
+	for _, sa := range a {
+		for _, sb := range b {
+			if len(result) == maxLabelSets {
+				return result
+			}
+			result = append(result, append(slices.Clone(sa), sb...))
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Lint(t *testing.T) {
+	cfg, err := ParseConfig(strings.NewReader(`
+rules:
+  external:
+    authorAssociation: [NONE]
+  unused:
+    type: issue
+teams:
+  loki:
+    requireLabel: [loki, logs]
+    members:
+      - name: alice
+        ical-url: https://example.com/alice.ics
+      - name: bob
+    secondary:
+      - name: Alice
+  mimir:
+    requireLabel:
+      all: [logs, metrics]
+    members:
+      - name: bob
+  tempo:
+    requireLabel:
+      all: [traces]
+      none: [loki, logs]
+    githubTeam: grafana/tempo
+  empty:
+    rule: external
+  unmatched:
+    members:
+      - name: carol
+        googleCalendar: carol@example.com
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`member "Alice" is listed more than once in team "loki"`,
+		`member "bob" has no calendar and is always considered available, unless the ical-urls input contains it`,
+		`rule "unused" is never referenced`,
+		`team "empty" has no members`,
+		`team "unmatched" never matches, as neither requireLabel nor rule is set`,
+		`teams "loki" and "mimir" overlap, issues labeled logs, metrics are assigned to members of both`,
+	}
+	var messages []string
+	for _, f := range cfg.Lint() {
+		messages = append(messages, f.Message)
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected findings\n%v\ngot\n%v", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
+
+func TestParseConfig_RejectsUnknownFields(t *testing.T) {
+	for name, raw := range map[string]string{
+		"root":   "unavailability: 6h",
+		"team":   "teams: {loki: {required_labels: [loki]}}",
+		"member": "teams: {loki: {members: [{name: alice, ical: https://example.com}]}}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseConfig(strings.NewReader(raw)); err == nil {
+				t.Error("expected unknown field to be rejected")
+			}
+		})
+	}
+}
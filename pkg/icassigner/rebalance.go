@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+)
+
+// DefaultRebalanceLookback is used if rebalanceLookback isn't configured.
+const DefaultRebalanceLookback = 7 * 24 * time.Hour
+
+// RebalanceMove is the reassignment of a single issue from an unavailable member to an available one.
+type RebalanceMove struct {
+	Number int
+	Title  string
+	Team   string
+
+	// From contains the unavailable members the issue is moved away from, separated by commas.
+	From string
+
+	// To is empty if no other member of the team is available.
+	To string
+}
+
+// RebalanceReport contains all reassignments done (or proposed in dry-run) by Rebalance.
+type RebalanceReport []RebalanceMove
+
+// Rebalance reassigns open issues which have been assigned by this action within the rebalance lookback to members
+// who are currently unavailable per calendar. The new assignees are chosen like for new issues, out of the available
+// members of the team. In dry-run the moves are only reported.
+func (a *Action) Rebalance(ctx context.Context, owner, repo string, now time.Time, dryRun bool) (RebalanceReport, error) {
+	issues, err := a.listAssignedIssues(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	lookback := a.Config.RebalanceLookback
+	if lookback == 0 {
+		lookback = DefaultRebalanceLookback
+	}
+
+	// calendars are checked once per member, also when choosing new assignees
+	a.availabilityCache = map[string]availabilityResult{}
+	defer func() { a.availabilityCache = nil }()
+
+	// the outputs list all issues handled instead of only the last one
+	a.assignments = []Assignment{}
+	defer a.reportAll()
+
+	isAvailable := func(m MemberConfig) bool {
+		available, err := a.availability(m)
+		if err != nil {
+			level.Warn(a.logger()).Log("msg", "unable to fetch availability", "member", m.Name, "err", err)
+		}
+		return available
+	}
+
+	// a failing issue, e.g. due to missing permissions, must not stop the rebalancing of all others
+	var report RebalanceReport
+	var errs []error
+	for _, iss := range issues {
+		if a.hasIgnoredLabel(iss) {
+			continue
+		}
+
+		team, teamName := findTeam(a.Config, iss, now)
+		teamMembers := team.allMembers()
+
+		// all unavailable holders are replaced at once, so that the issue gets a single new assignee
+		holders, err := a.unavailableHolders(ctx, iss, teamMembers, isAvailable, now.Add(-lookback))
+		if err != nil {
+			level.Error(a.logger()).Log("msg", "unable to rebalance issue", "issue", iss.Number, "err", err)
+			errs = append(errs, fmt.Errorf("unable to rebalance issue %d, due %w", iss.Number, err))
+			continue
+		}
+		if len(holders) == 0 {
+			continue
+		}
+
+		move := RebalanceMove{Number: iss.Number, Title: iss.Title, Team: teamName, From: strings.Join(holders, ", ")}
+
+		var unavailable []string
+		for _, m := range teamMembers {
+			if !isAvailable(m) {
+				unavailable = append(unavailable, m.Name)
+			}
+		}
+
+		// only move issues to someone available, everything else just creates noise
+		if len(unavailable) < len(teamMembers) {
+			move.To, err = a.assign(ctx, iss, team, teamName, unavailable, holders, dryRun)
+			if err != nil && !errors.Is(err, errAtCapacity) {
+				level.Error(a.logger()).Log("msg", "unable to reassign issue", "issue", iss.Number, "err", err)
+				errs = append(errs, fmt.Errorf("unable to reassign issue %d, due %w", iss.Number, err))
+				continue
+			}
+		}
+
+		report = append(report, move)
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// unavailableHolders returns the members responsible for the issue who are unavailable and have been assigned by this
+// action since the given time.
+func (a *Action) unavailableHolders(ctx context.Context, iss issue.Issue, teamMembers []MemberConfig, isAvailable func(MemberConfig) bool, since time.Time) ([]string, error) {
+	var holders []string
+	for _, holder := range assignedMembers(teamMembers, currentOwners(iss)) {
+		if isAvailable(memberByName(teamMembers, holder)) {
+			continue
+		}
+
+		recent, err := a.isRecentActionAssignment(ctx, iss, holder, since)
+		if err != nil {
+			return nil, err
+		}
+		if recent {
+			holders = append(holders, holder)
+		}
+	}
+	return holders, nil
+}
+
+// isRecentActionAssignment reports whether the member has been assigned to the issue by this action since the given time.
+func (a *Action) isRecentActionAssignment(ctx context.Context, iss issue.Issue, member string, since time.Time) (bool, error) {
+	login, err := a.login(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	timeline, err := a.listTimeline(ctx, iss)
+	if err != nil {
+		return false, err
+	}
+
+	assignedAt, byAction := assignedByAt(timeline, []string{member}, login)
+	return byAction && assignedAt.After(since), nil
+}
+
+func (a *Action) hasIgnoredLabel(iss issue.Issue) bool {
+	for _, l := range a.Config.IgnoredLabels {
+		if iss.HasLabel(l) {
+			return true
+		}
+	}
+	return false
+}
+
+func memberByName(members []MemberConfig, name string) MemberConfig {
+	for _, m := range members {
+		if strings.EqualFold(m.Name, name) {
+			return m
+		}
+	}
+	return MemberConfig{Name: name}
+}
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+)
+
+// outOfOfficeCalendar returns an ical calendar with an event blocking the whole time around now.
+func outOfOfficeCalendar(now time.Time) string {
+	return fmt.Sprintf(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:test
+X-WR-TIMEZONE:UTC
+BEGIN:VEVENT
+DTSTART:%s
+DTEND:%s
+DTSTAMP:%s
+UID:ooo@test
+SUMMARY:Vacation
+END:VEVENT
+END:VCALENDAR`, now.Add(-24*time.Hour).UTC().Format("20060102T150405Z"), now.Add(72*time.Hour).UTC().Format("20060102T150405Z"), now.UTC().Format("20060102T150405Z"))
+}
+
+func TestRebalance_DryRun(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+	outputFile := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+
+	now := time.Now()
+	outOfOffice := outOfOfficeCalendar(now)
+
+	calendarRequests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".ics") {
+			calendarRequests[r.URL.Path]++
+		}
+
+		switch {
+		case r.URL.Path == "/bob.ics":
+			w.Write([]byte(outOfOffice))
+		case r.URL.Path == "/alice.ics":
+			w.Write([]byte("BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:test\nEND:VCALENDAR"))
+		case r.URL.Path == "/repos/owner/repo/issues" && r.URL.Query().Get("assignee") == "*":
+			w.Write([]byte(`[
+				{"number": 1, "title": "recently assigned", "state": "open", "labels": [{"name": "loki"}], "assignees": [{"login": "bob"}]},
+				{"number": 2, "title": "assigned long ago", "state": "open", "labels": [{"name": "loki"}], "assignees": [{"login": "bob"}]},
+				{"number": 3, "title": "assigned to alice", "state": "open", "labels": [{"name": "loki"}], "assignees": [{"login": "alice"}]},
+				{"number": 4, "title": "timeline fails", "state": "open", "labels": [{"name": "loki"}], "assignees": [{"login": "bob"}]}
+			]`))
+		case r.URL.Path == "/repos/owner/repo/issues":
+			// busyness of a member
+			w.Write([]byte(`[]`))
+		case r.URL.Path == "/repos/owner/repo/issues/1/timeline":
+			json.NewEncoder(w).Encode([]*github.Timeline{timelineEvent("assigned", "github-actions[bot]", "Bot", "bob", now.Add(-24*time.Hour))})
+		case r.URL.Path == "/repos/owner/repo/issues/2/timeline":
+			json.NewEncoder(w).Encode([]*github.Timeline{timelineEvent("assigned", "github-actions[bot]", "Bot", "bob", now.Add(-30*24*time.Hour))})
+		case r.URL.Path == "/repos/owner/repo/issues/4/timeline":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Forbidden"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	a := &Action{Client: gh, Login: DefaultLogin, Config: Config{
+		UnavailabilityLimit: 6 * time.Hour,
+		Teams: map[string]TeamConfig{
+			"loki": {
+				RequireLabel: labelexpr.Label("loki"),
+				Members: []MemberConfig{
+					{Name: "alice", IcalURL: server.URL + "/alice.ics"},
+					{Name: "bob", IcalURL: server.URL + "/bob.ics"},
+				},
+			},
+		},
+	}}
+
+	// the failing issue is reported, but doesn't stop the others from being rebalanced
+	report, err := a.Rebalance(context.Background(), "owner", "repo", now, true)
+	if err == nil || !strings.Contains(err.Error(), "issue 4") {
+		t.Errorf("expected the error of issue 4 to be returned, got %v", err)
+	}
+
+	expected := RebalanceReport{{Number: 1, Title: "recently assigned", Team: "loki", From: "bob", To: "alice"}}
+	if fmt.Sprint(report) != fmt.Sprint(expected) {
+		t.Errorf("expected report %v, got %v", expected, report)
+	}
+
+	for path, count := range calendarRequests {
+		if count != 1 {
+			t.Errorf("expected calendar %s to be fetched once, got %d", path, count)
+		}
+	}
+
+	// the outputs of single assignments aren't set, as they would only describe the last issue
+	outputs := readOutputs(t, outputFile)
+	if len(outputs) != 2 || outputs["issues"] != "[1]" || outputs["assignees"] != `["alice"]` {
+		t.Errorf("expected the outputs to list all moved issues once, got %v", outputs)
+	}
+}
+
+func TestRebalance_ReplacesAllUnavailableHoldersAtOnce(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "sha")
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+	t.Setenv("GITHUB_OUTPUT", filepath.Join(t.TempDir(), "output"))
+
+	now := time.Now()
+	outOfOffice := outOfOfficeCalendar(now)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/ooo.ics":
+			w.Write([]byte(outOfOffice))
+		case r.URL.Path == "/free.ics":
+			w.Write([]byte("BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:test\nEND:VCALENDAR"))
+		case r.URL.Path == "/repos/owner/repo/issues" && r.URL.Query().Get("assignee") == "*":
+			w.Write([]byte(`[{"number": 1, "title": "two holders", "state": "open", "labels": [{"name": "loki"}], "assignees": [{"login": "bob"}, {"login": "carol"}]}]`))
+		case r.URL.Path == "/repos/owner/repo/issues" && r.Method == http.MethodGet:
+			w.Write([]byte(`[]`))
+		case r.URL.Path == "/repos/owner/repo/issues/1/timeline":
+			json.NewEncoder(w).Encode([]*github.Timeline{
+				timelineEvent("assigned", "github-actions[bot]", "Bot", "bob", now.Add(-24*time.Hour)),
+				timelineEvent("assigned", "github-actions[bot]", "Bot", "carol", now.Add(-24*time.Hour)),
+			})
+		case r.URL.Path == "/repos/owner/repo/issues/1/assignees":
+			body, _ := io.ReadAll(r.Body)
+			requests = append(requests, r.Method+" "+strings.TrimSpace(string(body)))
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	a := &Action{Client: gh, Login: DefaultLogin, Config: Config{
+		UnavailabilityLimit: 6 * time.Hour,
+		Teams: map[string]TeamConfig{
+			"loki": {
+				RequireLabel: labelexpr.Label("loki"),
+				Members: []MemberConfig{
+					{Name: "alice", IcalURL: server.URL + "/free.ics"},
+					{Name: "bob", IcalURL: server.URL + "/ooo.ics"},
+					{Name: "carol", IcalURL: server.URL + "/ooo.ics"},
+				},
+			},
+		},
+	}}
+
+	report, err := a.Rebalance(context.Background(), "owner", "repo", now, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := RebalanceReport{{Number: 1, Title: "two holders", Team: "loki", From: "bob, carol", To: "alice"}}
+	if fmt.Sprint(report) != fmt.Sprint(expected) {
+		t.Errorf("expected report %v, got %v", expected, report)
+	}
+
+	expectedRequests := []string{`POST {"assignees":["alice"]}`, `DELETE {"assignees":["bob","carol"]}`}
+	if strings.Join(requests, "\n") != strings.Join(expectedRequests, "\n") {
+		t.Errorf("expected a single reassignment, got %q", requests)
+	}
+}
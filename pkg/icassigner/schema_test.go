@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grafana/escalation-scheduler/pkg/rules"
+)
+
+// TestSchema_CoversConfig ensures the published schema knows every field of the config, as it rejects unknown fields.
+func TestSchema_CoversConfig(t *testing.T) {
+	raw, err := os.ReadFile("../../schemas/ic-assignment.schema.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("unable to parse schema: %v", err)
+	}
+
+	definitions := schema["definitions"].(map[string]interface{})
+	for name, tc := range map[string]struct {
+		schema interface{}
+		typ    reflect.Type
+	}{
+		"root":       {schema, reflect.TypeOf(Config{})},
+		"team":       {definitions["team"], reflect.TypeOf(TeamConfig{})},
+		"member":     {definitions["member"], reflect.TypeOf(MemberConfig{})},
+		"codeowners": {definitions["codeowners"], reflect.TypeOf(CodeOwnersConfig{})},
+		"rule":       {definitions["rule"], reflect.TypeOf(rules.Rule{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := tc.schema.(map[string]interface{})
+			if s["additionalProperties"] != false {
+				t.Error("expected schema to reject additional properties")
+			}
+
+			var properties []string
+			for k := range s["properties"].(map[string]interface{}) {
+				properties = append(properties, k)
+			}
+			sort.Strings(properties)
+
+			if fields := yamlFields(tc.typ); !reflect.DeepEqual(fields, properties) {
+				t.Errorf("expected schema properties %v, got %v", fields, properties)
+			}
+		})
+	}
+}
+
+func yamlFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grafana/escalation-scheduler/pkg/redact"
+)
+
+// ResolveCalendarSecrets sets the ical-url of members which reference it indirectly, either by the environment variable
+// named by their ical-url-env, or by their name in icalURLs, a JSON object mapping member names to URLs (the ical-urls
+// input). An ical-url-env takes precedence over icalURLs, members with an ical-url or googleCalendar are left as they are.
+// The resolved URLs are masked and redacted from logs, as private feed URLs grant access to the calendar.
+func (c *Config) ResolveCalendarSecrets(icalURLs string) error {
+	byName := map[string]string{}
+	if icalURLs != "" {
+		var urls map[string]string
+		if err := json.Unmarshal([]byte(icalURLs), &urls); err != nil {
+			// the error can contain parts of the secret
+			return fmt.Errorf("unable to parse ical-urls, it needs to be a JSON object mapping member names to URLs")
+		}
+
+		for name, url := range urls {
+			byName[strings.ToLower(name)] = url
+		}
+	}
+
+	for name, t := range c.Teams {
+		for _, members := range [][]MemberConfig{t.Members, t.Secondary, t.Manager} {
+			// members is a slice of the team, so changes are written to the team directly
+			for idx := range members {
+				m := &members[idx]
+				if m.IcalURL != "" || m.GoogleCalendar != "" {
+					continue
+				}
+
+				if m.IcalURLEnv != "" {
+					m.IcalURL = os.Getenv(m.IcalURLEnv)
+					if m.IcalURL == "" {
+						return fmt.Errorf("environment variable %q of member %q in team %q is not set", m.IcalURLEnv, m.Name, name)
+					}
+				} else {
+					m.IcalURL = byName[strings.ToLower(m.Name)]
+				}
+
+				if m.IcalURL != "" {
+					redact.Mask(m.IcalURL)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveCalendarSecrets(t *testing.T) {
+	t.Setenv("ALICE_ICAL", "https://example.com/alice-secret.ics")
+
+	cfg, err := ParseConfig(strings.NewReader(`
+teams:
+  loki:
+    requireLabel: [loki]
+    members:
+      - name: alice
+        ical-url-env: ALICE_ICAL
+      - name: bob
+      - name: carol
+        ical-url: https://example.com/carol.ics
+    secondary:
+      - name: dave
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = cfg.ResolveCalendarSecrets(`{"Bob": "https://example.com/bob-secret.ics", "carol": "https://example.com/ignored.ics"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	team := cfg.Teams["loki"]
+	for idx, expected := range []string{"https://example.com/alice-secret.ics", "https://example.com/bob-secret.ics", "https://example.com/carol.ics"} {
+		if team.Members[idx].IcalURL != expected {
+			t.Errorf("expected ical-url %q for %q, got %q", expected, team.Members[idx].Name, team.Members[idx].IcalURL)
+		}
+	}
+	if team.Secondary[0].IcalURL != "" {
+		t.Errorf("expected dave to stay without calendar, got %q", team.Secondary[0].IcalURL)
+	}
+}
+
+func TestResolveCalendarSecrets_Errors(t *testing.T) {
+	cfg, err := ParseConfig(strings.NewReader(`
+teams:
+  loki:
+    requireLabel: [loki]
+    members:
+      - name: alice
+        ical-url-env: MISSING_ICAL
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cfg.ResolveCalendarSecrets(""); err == nil {
+		t.Error("expected unset environment variable to be rejected")
+	}
+
+	err = cfg.ResolveCalendarSecrets(`{"alice": "https://example.com/secret-token.ics"`)
+	if err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("expected invalid JSON to be rejected without leaking its content, got %v", err)
+	}
+}
+
+func TestParseConfig_IcalURLAndEnv(t *testing.T) {
+	_, err := ParseConfig(strings.NewReader(`
+teams:
+  loki:
+    requireLabel: [loki]
+    members:
+      - name: alice
+        ical-url: https://example.com/alice.ics
+        ical-url-env: ALICE_ICAL
+`))
+	if err == nil {
+		t.Error("expected member with ical-url and ical-url-env to be rejected")
+	}
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
+)
+
+const (
+	// AvailabilityAvailable means the calendar of the member doesn't block the assignment.
+	AvailabilityAvailable = "available"
+
+	// AvailabilityUnavailable means the member is busy due to an event in their calendar.
+	AvailabilityUnavailable = "unavailable"
+
+	// AvailabilityAtCapacity means the member has reached the capacity configured for the team.
+	AvailabilityAtCapacity = "at capacity"
+
+	// AvailabilityNotChecked means the calendar of the member hasn't been checked, as less busy members are available.
+	AvailabilityNotChecked = "not checked"
+)
+
+// Candidate is a member of the team which has been considered for an assignment.
+type Candidate struct {
+	Name         string  `json:"name"`
+	Tier         string  `json:"tier"`
+	Busyness     float64 `json:"busyness"`
+	Availability string  `json:"availability"`
+
+	// Reason explains the availability, e.g. the error if the calendar couldn't be fetched.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Assignment explains how the member assigned to an issue has been chosen.
+type Assignment struct {
+	Issue      int
+	Team       string
+	Candidates []Candidate
+
+	// Fallback explains why members have been considered available despite their calendar or capacity, empty if not.
+	Fallback string
+
+	// Chosen is the name of the chosen member, empty if nobody has been chosen.
+	Chosen string
+
+	// Output is the output property of the chosen member, empty if it isn't configured.
+	Output string
+	DryRun bool
+
+	// Skipped explains why no member has been considered at all, e.g. because no team is responsible for the issue.
+	Skipped string
+}
+
+// Markdown renders the assignment as section of the job summary.
+func (as Assignment) Markdown() string {
+	var sb strings.Builder
+
+	if as.Skipped != "" {
+		fmt.Fprintf(&sb, "### Assignment of #%d\n\nSkipped, as %s.\n", as.Issue, as.Skipped)
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "### Assignment of #%d to team %q\n\n", as.Issue, as.Team)
+
+	rows := make([][]string, 0, len(as.Candidates))
+	for _, c := range as.Candidates {
+		rows = append(rows, []string{c.Name, c.Tier, strconv.FormatFloat(c.Busyness, 'g', -1, 64), c.Availability, c.Reason})
+	}
+	sb.WriteString(githubaction.MarkdownTable([]string{"Member", "Tier", "Busyness", "Availability", "Reason"}, rows))
+
+	if as.Fallback != "" {
+		fmt.Fprintf(&sb, "\n%s\n", as.Fallback)
+	}
+
+	if as.Chosen != "" {
+		fmt.Fprintf(&sb, "\nChosen member: **%s**\n", as.Chosen)
+	} else {
+		sb.WriteString("\nNobody has been chosen.\n")
+	}
+
+	if as.DryRun {
+		sb.WriteString("\nDry-run is enabled, the issue hasn't been changed.\n")
+	}
+
+	return sb.String()
+}
+
+// Outputs returns the outputs of the action describing the assignment.
+func (as Assignment) Outputs() (map[string]string, error) {
+	candidates := as.Candidates
+	if candidates == nil {
+		candidates = []Candidate{}
+	}
+
+	availability := make(map[string]string, len(as.Candidates))
+	busyness := make(map[string]float64, len(as.Candidates))
+	for _, c := range as.Candidates {
+		availability[c.Name] = c.Availability
+		busyness[c.Name] = c.Busyness
+	}
+
+	candidatesJSON, err := json.Marshal(candidates)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode candidates, due %w", err)
+	}
+	availabilityJSON, err := json.Marshal(availability)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode availability, due %w", err)
+	}
+	busynessJSON, err := json.Marshal(busyness)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode busyness, due %w", err)
+	}
+
+	assignee := as.Output
+	if assignee == "" {
+		assignee = as.Chosen
+	}
+
+	return map[string]string{
+		"assignee":        assignee,
+		"assignee-login":  as.Chosen,
+		"assignee-output": as.Output,
+		"team":            as.Team,
+		"candidates":      string(candidatesJSON),
+		"availability":    string(availabilityJSON),
+		"busyness-report": string(busynessJSON),
+		"fallback-used":   strconv.FormatBool(as.Fallback != ""),
+		"skipped-reason":  as.Skipped,
+	}, nil
+}
+
+// multiOutputs converts the assignments of a run handling multiple issues into outputs of the action, which list the
+// issues and the members chosen for them in the same order. Nobody chosen is represented by an empty string.
+func multiOutputs(assignments []Assignment) (map[string]string, error) {
+	issues := make([]int, 0, len(assignments))
+	assignees := make([]string, 0, len(assignments))
+	for _, as := range assignments {
+		issues = append(issues, as.Issue)
+		assignees = append(assignees, as.Chosen)
+	}
+
+	issuesJSON, err := json.Marshal(issues)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode issues, due %w", err)
+	}
+	assigneesJSON, err := json.Marshal(assignees)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode assignees, due %w", err)
+	}
+
+	return map[string]string{
+		"issues":    string(issuesJSON),
+		"assignees": string(assigneesJSON),
+	}, nil
+}
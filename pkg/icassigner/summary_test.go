@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icassigner
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignment_Markdown(t *testing.T) {
+	as := Assignment{
+		Issue: 12,
+		Team:  "loki",
+		Candidates: []Candidate{
+			{Name: "alice", Tier: "primary", Busyness: 0.5, Availability: AvailabilityUnavailable, Reason: "busy due to a calendar event"},
+			{Name: "bob", Tier: "primary", Busyness: 3, Availability: AvailabilityAtCapacity, Reason: "capacity of 3 issues reached"},
+		},
+		Fallback: `Nobody seems to be available, hence everybody of tier "primary" with capacity left has been considered available.`,
+		Chosen:   "alice",
+		DryRun:   true,
+	}
+
+	require.Equal(t, `### Assignment of #12 to team "loki"
+
+| Member | Tier | Busyness | Availability | Reason |
+| --- | --- | --- | --- | --- |
+| alice | primary | 0.5 | unavailable | busy due to a calendar event |
+| bob | primary | 3 | at capacity | capacity of 3 issues reached |
+
+Nobody seems to be available, hence everybody of tier "primary" with capacity left has been considered available.
+
+Chosen member: **alice**
+
+Dry-run is enabled, the issue hasn't been changed.
+`, as.Markdown())
+}
+
+func TestAssignment_MarkdownSkipped(t *testing.T) {
+	as := Assignment{Issue: 12, Skipped: "no team is responsible for the issue"}
+	require.Equal(t, "### Assignment of #12\n\nSkipped, as no team is responsible for the issue.\n", as.Markdown())
+}
+
+func TestAssignment_Outputs(t *testing.T) {
+	as := Assignment{
+		Issue: 12,
+		Team:  "loki",
+		Candidates: []Candidate{
+			{Name: "alice", Tier: "primary", Busyness: 0.5, Availability: AvailabilityAvailable},
+			{Name: "bob", Tier: "primary", Busyness: 3, Availability: AvailabilityAtCapacity, Reason: "capacity of 3 issues reached"},
+		},
+		Chosen: "alice",
+		Output: "@alice-slack",
+	}
+
+	outputs, err := as.Outputs()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"assignee":        "@alice-slack",
+		"assignee-login":  "alice",
+		"assignee-output": "@alice-slack",
+		"team":            "loki",
+		"candidates":      `[{"name":"alice","tier":"primary","busyness":0.5,"availability":"available"},{"name":"bob","tier":"primary","busyness":3,"availability":"at capacity","reason":"capacity of 3 issues reached"}]`,
+		"availability":    `{"alice":"available","bob":"at capacity"}`,
+		"busyness-report": `{"alice":0.5,"bob":3}`,
+		"fallback-used":   "false",
+		"skipped-reason":  "",
+	}, outputs)
+
+	outputs, err = Assignment{Issue: 12, Skipped: "no team is responsible for the issue"}.Outputs()
+	require.NoError(t, err)
+	require.Equal(t, "", outputs["assignee"])
+	require.Equal(t, "[]", outputs["candidates"])
+	require.Equal(t, "no team is responsible for the issue", outputs["skipped-reason"])
+}
+
+func TestMultiOutputs(t *testing.T) {
+	outputs, err := multiOutputs([]Assignment{
+		{Issue: 12, Team: "loki", Chosen: "alice", Output: "@alice-slack"},
+		{Issue: 15, Skipped: "no team is responsible for the issue"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"issues":    "[12,15]",
+		"assignees": `["alice",""]`,
+	}, outputs)
+
+	outputs, err = multiOutputs(nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"issues": "[]", "assignees": "[]"}, outputs)
+}
+
+// readOutputs parses the outputs written to the github output file, later values overwrite earlier ones.
+func readOutputs(t *testing.T, file string) map[string]string {
+	t.Helper()
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	outputs := map[string]string{}
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i < len(lines); i++ {
+		name, delimiter, ok := strings.Cut(lines[i], "<<")
+		if !ok {
+			continue
+		}
+
+		var value []string
+		for i++; i < len(lines) && lines[i] != delimiter; i++ {
+			value = append(value, lines[i])
+		}
+		outputs[name] = strings.Join(value, "\n")
+	}
+	return outputs
+}
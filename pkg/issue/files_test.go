@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListChangedFiles_Pagination(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/repos/owner/repo/pulls/1/files", r.URL.Path)
+
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"filename": "docs/index.md"}]`))
+			return
+		}
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/pulls/1/files?page=2>; rel="next"`, server.URL))
+		w.Write([]byte(`[{"filename": "pkg/ingester/ingester.go"}, {"filename": "go.mod"}]`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	files, err := ListChangedFiles(context.Background(), gh, "owner", "repo", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"pkg/ingester/ingester.go", "go.mod", "docs/index.md"}, files)
+}
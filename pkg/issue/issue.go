@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package issue contains the common model of issues and pull requests used by all actions.
+package issue
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Issue is the common model of an issue or pull request, independent of the event it has been received with.
+type Issue struct {
+	Owner  string
+	Repo   string
+	Number int
+
+	Title string
+	Body  string
+	State string
+
+	Labels    []string
+	Assignees []string
+	Milestone string
+
+	Author string
+	// AuthorAssociation is the relationship of the author to the repository, e.g. MEMBER or CONTRIBUTOR.
+	AuthorAssociation string
+
+	IsPullRequest bool
+	CreatedAt     time.Time
+
+	// RequestedReviewers contains the logins of users requested to review a pull request.
+	// It's only known for pull request events, not for issue comments on pull requests.
+	RequestedReviewers []string
+
+	// HeadSHA is the commit at the head of a pull request. It's only known for pull request events.
+	HeadSHA string
+
+	// ChangedFiles contains the paths of all files changed by a pull request.
+	// It's not part of any event and needs to be fetched with ListChangedFiles.
+	ChangedFiles []string
+}
+
+// FromGithub converts an issue as returned by the github API.
+// The author association is not exposed by go-github, use Parse to retain it.
+func FromGithub(i *github.Issue) Issue {
+	result := Issue{
+		Number:        i.GetNumber(),
+		Title:         i.GetTitle(),
+		Body:          i.GetBody(),
+		State:         i.GetState(),
+		Labels:        make([]string, 0, len(i.Labels)),
+		Milestone:     i.GetMilestone().GetTitle(),
+		Author:        i.GetUser().GetLogin(),
+		IsPullRequest: i.PullRequestLinks != nil,
+		CreatedAt:     i.GetCreatedAt(),
+	}
+
+	if i.RepositoryURL != nil {
+		result.Owner, result.Repo = decomposeRepoURL(i.GetRepositoryURL())
+	}
+
+	for _, l := range i.Labels {
+		result.Labels = append(result.Labels, l.GetName())
+	}
+
+	for _, a := range i.Assignees {
+		result.Assignees = append(result.Assignees, a.GetLogin())
+	}
+
+	return result
+}
+
+// Parse converts the JSON representation of an issue as returned by the github API or contained in webhook payloads.
+func Parse(raw json.RawMessage) (Issue, error) {
+	var payload struct {
+		github.Issue
+		AuthorAssociation string `json:"author_association"`
+	}
+
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return Issue{}, fmt.Errorf("unable to parse issue, due %w", err)
+	}
+
+	result := FromGithub(&payload.Issue)
+	result.AuthorAssociation = payload.AuthorAssociation
+	return result, nil
+}
+
+// ParsePullRequest converts the JSON representation of a pull request as contained in webhook payloads.
+// As pull requests don't reference their repository by url, Owner and Repo need to be set by the caller.
+func ParsePullRequest(raw json.RawMessage) (Issue, error) {
+	var pr github.PullRequest
+	if err := json.Unmarshal(raw, &pr); err != nil {
+		return Issue{}, fmt.Errorf("unable to parse pull request, due %w", err)
+	}
+
+	result := Issue{
+		Number:            pr.GetNumber(),
+		Title:             pr.GetTitle(),
+		Body:              pr.GetBody(),
+		State:             pr.GetState(),
+		Labels:            make([]string, 0, len(pr.Labels)),
+		Milestone:         pr.GetMilestone().GetTitle(),
+		Author:            pr.GetUser().GetLogin(),
+		AuthorAssociation: pr.GetAuthorAssociation(),
+		IsPullRequest:     true,
+		CreatedAt:         pr.GetCreatedAt(),
+		HeadSHA:           pr.GetHead().GetSHA(),
+	}
+
+	for _, l := range pr.Labels {
+		result.Labels = append(result.Labels, l.GetName())
+	}
+
+	for _, a := range pr.Assignees {
+		result.Assignees = append(result.Assignees, a.GetLogin())
+	}
+
+	for _, r := range pr.RequestedReviewers {
+		result.RequestedReviewers = append(result.RequestedReviewers, r.GetLogin())
+	}
+
+	return result, nil
+}
+
+// HasLabel reports whether the issue carries the given label.
+func (i Issue) HasLabel(label string) bool {
+	return slices.Contains(i.Labels, label)
+}
+
+// decomposeRepoURL extracts owner and name of a repository from its API url, e.g. https://api.github.com/repos/grafana/grafana
+func decomposeRepoURL(repoURL string) (repoOwner, repoName string) {
+	repoUrlSplit := strings.Split(repoURL, "/")
+	repoName = repoUrlSplit[len(repoUrlSplit)-1]
+	if len(repoUrlSplit) > 1 {
+		repoOwner = repoUrlSplit[len(repoUrlSplit)-2]
+	}
+	return repoOwner, repoName
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecomposeRepoURL(t *testing.T) {
+	testCases := []struct {
+		url           string
+		expectedOwner string
+		expectedName  string
+	}{
+		{
+			url:           "https://api.github.com/repos/grafana/grafana",
+			expectedOwner: "grafana",
+			expectedName:  "grafana",
+		},
+		{
+			url:           "https://api.github.com/repos/my-org/my-repo",
+			expectedOwner: "my-org",
+			expectedName:  "my-repo",
+		},
+		{
+			url:           "/repos/owner/repo",
+			expectedOwner: "owner",
+			expectedName:  "repo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			owner, name := decomposeRepoURL(tc.url)
+			require.Equal(t, tc.expectedOwner, owner)
+			require.Equal(t, tc.expectedName, name)
+		})
+	}
+}
+
+func TestFromGithub_Labels(t *testing.T) {
+	t.Run("returns label names", func(t *testing.T) {
+		issue := &github.Issue{
+			Labels: []github.Label{
+				{Name: github.String("bug")},
+				{Name: github.String("enhancement")},
+			},
+		}
+		require.Equal(t, []string{"bug", "enhancement"}, FromGithub(issue).Labels)
+	})
+
+	t.Run("returns empty slice for issue with no labels", func(t *testing.T) {
+		issue := &github.Issue{Labels: []github.Label{}}
+		require.Empty(t, FromGithub(issue).Labels)
+	})
+}
+
+func TestParse(t *testing.T) {
+	raw := `{
+		"number": 12,
+		"title": "Loki is slow",
+		"body": "some body",
+		"state": "open",
+		"user": {"login": "someone"},
+		"author_association": "CONTRIBUTOR",
+		"labels": [{"name": "bug"}],
+		"assignees": [{"login": "alice"}],
+		"milestone": {"title": "v1.0"},
+		"repository_url": "https://api.github.com/repos/grafana/loki",
+		"pull_request": {"url": "https://api.github.com/repos/grafana/loki/pulls/12"},
+		"created_at": "2024-01-02T03:04:05Z"
+	}`
+
+	iss, err := Parse([]byte(raw))
+	require.NoError(t, err)
+	require.Equal(t, Issue{
+		Owner:             "grafana",
+		Repo:              "loki",
+		Number:            12,
+		Title:             "Loki is slow",
+		Body:              "some body",
+		State:             "open",
+		Labels:            []string{"bug"},
+		Assignees:         []string{"alice"},
+		Milestone:         "v1.0",
+		Author:            "someone",
+		AuthorAssociation: "CONTRIBUTOR",
+		IsPullRequest:     true,
+		CreatedAt:         time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+	}, iss)
+	require.True(t, iss.HasLabel("bug"))
+}
+
+func TestParsePullRequest(t *testing.T) {
+	raw := `{
+		"number": 13,
+		"title": "Speed up Loki",
+		"body": "some body",
+		"state": "open",
+		"user": {"login": "someone"},
+		"author_association": "MEMBER",
+		"labels": [{"name": "enhancement"}],
+		"assignees": [{"login": "alice"}],
+		"requested_reviewers": [{"login": "bob"}],
+		"head": {"sha": "abc123"},
+		"created_at": "2024-01-02T03:04:05Z"
+	}`
+
+	iss, err := ParsePullRequest([]byte(raw))
+	require.NoError(t, err)
+	require.Equal(t, Issue{
+		Number:             13,
+		Title:              "Speed up Loki",
+		Body:               "some body",
+		State:              "open",
+		Labels:             []string{"enhancement"},
+		Assignees:          []string{"alice"},
+		Author:             "someone",
+		AuthorAssociation:  "MEMBER",
+		IsPullRequest:      true,
+		CreatedAt:          time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		RequestedReviewers: []string{"bob"},
+		HeadSHA:            "abc123",
+	}, iss)
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+)
+
+// NoLabel is used in backtest reports for issues which didn't get (or didn't have) any of the configured labels.
+const NoLabel = "(none)"
+
+// Example is a historical issue together with the labels it ended up with.
+type Example struct {
+	Number int
+	Title  string
+	Body   string
+	Labels []string
+}
+
+// ReadExamples reads a JSONL stream of github issues, one issue per line, as returned by the github API.
+// Such an export can e.g. be created via `gh api --paginate "repos/<owner>/<repo>/issues?state=closed" | jq -c '.[]'`.
+func ReadExamples(r io.Reader) ([]Example, error) {
+	var examples []Example
+
+	scanner := bufio.NewScanner(r)
+	// issue bodies can easily exceed the default token size of 64KiB
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		iss, err := issue.Parse(json.RawMessage(line))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse issue in line %d, due %w", lineNo, err)
+		}
+
+		examples = append(examples, Example{
+			Number: iss.Number,
+			Title:  iss.Title,
+			Body:   iss.Body,
+			Labels: iss.Labels,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read examples, due %w", err)
+	}
+
+	return examples, nil
+}
+
+// LabelMetrics contains the quality metrics of a single label in a backtest.
+type LabelMetrics struct {
+	Label          string
+	TruePositives  int
+	FalsePositives int
+	FalseNegatives int
+}
+
+// Precision is the share of issues which got this label predicted and actually carry it.
+func (m LabelMetrics) Precision() float64 {
+	return ratio(m.TruePositives, m.TruePositives+m.FalsePositives)
+}
+
+// Recall is the share of issues carrying this label which also got it predicted.
+func (m LabelMetrics) Recall() float64 {
+	return ratio(m.TruePositives, m.TruePositives+m.FalseNegatives)
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// BacktestReport summarizes how well a config predicts the labels of historical issues.
+type BacktestReport struct {
+	Total   int
+	Correct int
+
+	// Labels contains the metrics of every configured label, sorted by label name.
+	Labels []LabelMetrics
+
+	// Confusion counts issues by their actual label (first key) and their predicted label (second key).
+	// Issues without any configured label, or without a prediction, are counted as NoLabel.
+	Confusion map[string]map[string]int
+}
+
+// Accuracy is the share of examples for which the predicted label matches the actual one.
+func (r BacktestReport) Accuracy() float64 {
+	return ratio(r.Correct, r.Total)
+}
+
+// ConfusionLabels returns all labels used in the confusion matrix, sorted by name with NoLabel last.
+func (r BacktestReport) ConfusionLabels() []string {
+	set := map[string]struct{}{}
+	for actual, predictions := range r.Confusion {
+		set[actual] = struct{}{}
+		for predicted := range predictions {
+			set[predicted] = struct{}{}
+		}
+	}
+
+	labels := make([]string, 0, len(set))
+	for l := range set {
+		if l != NoLabel {
+			labels = append(labels, l)
+		}
+	}
+	sort.Strings(labels)
+
+	if _, ok := set[NoLabel]; ok {
+		labels = append(labels, NoLabel)
+	}
+	return labels
+}
+
+// Backtest runs the label selection against every example and compares the result to the labels the example ended up with.
+//
+// Examples are evaluated by title and body only, rules referring to other properties of an issue don't match.
+// Only configured labels are taken into account. If an example carries multiple configured labels, the prediction
+// is considered correct if it is one of them, otherwise the alphabetically first one is used as actual label.
+func (l *Labeler) Backtest(examples []Example) BacktestReport {
+	metrics := make(map[string]*LabelMetrics, len(l.cfg.Labels))
+	for label := range l.cfg.Labels {
+		metrics[label] = &LabelMetrics{Label: label}
+	}
+
+	report := BacktestReport{
+		Confusion: map[string]map[string]int{},
+	}
+
+	for _, e := range examples {
+		predicted, err := l.findLabel(issue.Issue{Number: e.Number, Title: e.Title, Body: e.Body})
+		if err != nil {
+			predicted = NoLabel
+		}
+
+		actual := l.actualLabel(e.Labels, predicted)
+
+		report.Total++
+		if actual == predicted {
+			report.Correct++
+		}
+
+		if report.Confusion[actual] == nil {
+			report.Confusion[actual] = map[string]int{}
+		}
+		report.Confusion[actual][predicted]++
+
+		if actual == predicted {
+			if m, ok := metrics[actual]; ok {
+				m.TruePositives++
+			}
+			continue
+		}
+
+		if m, ok := metrics[predicted]; ok {
+			m.FalsePositives++
+		}
+		if m, ok := metrics[actual]; ok {
+			m.FalseNegatives++
+		}
+	}
+
+	for _, m := range metrics {
+		report.Labels = append(report.Labels, *m)
+	}
+	sort.Slice(report.Labels, func(i, j int) bool {
+		return report.Labels[i].Label < report.Labels[j].Label
+	})
+
+	return report
+}
+
+// actualLabel determines the configured label an example ended up with.
+func (l *Labeler) actualLabel(issueLabels []string, predicted string) string {
+	var configured []string
+	for _, label := range issueLabels {
+		if _, ok := l.cfg.Labels[label]; ok {
+			configured = append(configured, label)
+		}
+	}
+
+	if len(configured) == 0 {
+		return NoLabel
+	}
+
+	if slices.Contains(configured, predicted) {
+		return predicted
+	}
+
+	sort.Strings(configured)
+	return configured[0]
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadExamples(t *testing.T) {
+	raw := `{"number":1,"title":"slow query","body":"body 1","labels":[{"name":"query"},{"name":"bug"}]}
+
+{"number":2,"title":"ingest broken","labels":[]}
+`
+	examples, err := ReadExamples(strings.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, []Example{
+		{Number: 1, Title: "slow query", Body: "body 1", Labels: []string{"query", "bug"}},
+		{Number: 2, Title: "ingest broken", Labels: []string{}},
+	}, examples)
+}
+
+func TestReadExamples_InvalidLine(t *testing.T) {
+	_, err := ReadExamples(strings.NewReader("{\"number\":1}\nnot json\n"))
+	require.ErrorContains(t, err, "line 2")
+}
+
+func TestBacktest(t *testing.T) {
+	l := &Labeler{
+		cfg: Config{
+			Labels: map[string]Label{
+				"query":  {Matchers: []Matcher{{regex: regexp.MustCompile(`query`), Weight: 1}}},
+				"ingest": {Matchers: []Matcher{{regex: regexp.MustCompile(`ingest`), Weight: 1}}},
+			},
+		},
+		logger: log.NewNopLogger(),
+	}
+
+	report := l.Backtest([]Example{
+		{Title: "slow query", Labels: []string{"query", "bug"}},   // correct
+		{Title: "ingest broken", Labels: []string{"ingest"}},      // correct
+		{Title: "query during ingest", Labels: []string{"query"}}, // tie broken by name: ingest, wrong
+		{Title: "something else", Labels: []string{"ingest"}},     // no prediction
+		{Title: "unrelated", Labels: []string{"bug"}},             // correct, nothing to predict
+		{Title: "query", Labels: []string{"ingest", "query"}},     // correct, one of multiple labels
+	})
+
+	require.Equal(t, 6, report.Total)
+	require.Equal(t, 4, report.Correct)
+	require.Equal(t, []LabelMetrics{
+		{Label: "ingest", TruePositives: 1, FalsePositives: 1, FalseNegatives: 1},
+		{Label: "query", TruePositives: 2, FalseNegatives: 1},
+	}, report.Labels)
+	require.Equal(t, map[string]map[string]int{
+		"query":  {"query": 2, "ingest": 1},
+		"ingest": {"ingest": 1, NoLabel: 1},
+		NoLabel:  {NoLabel: 1},
+	}, report.Confusion)
+	require.Equal(t, []string{"ingest", "query", NoLabel}, report.ConfusionLabels())
+
+	require.InDelta(t, 0.5, report.Labels[0].Precision(), 0.001)
+	require.InDelta(t, 2.0/3.0, report.Labels[1].Recall(), 0.001)
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// bayesModel is a multinomial naive bayes classifier over the words of issue titles and bodies.
+type bayesModel struct {
+	// logPriors contains the log probability of every label
+	logPriors map[string]float64
+
+	// logLikelihoods contains the log probability of every known word per label
+	logLikelihoods map[string]map[string]float64
+
+	// logUnknown contains the log probability per label of a word which has been seen for other labels only
+	logUnknown map[string]float64
+
+	vocabulary map[string]struct{}
+}
+
+// trainBayesModel trains a model for the given labels. Examples carrying none of the labels are ignored,
+// examples carrying multiple of them are used for each of them.
+func trainBayesModel(examples []Example, labels []string) (*bayesModel, error) {
+	docCount := map[string]int{}
+	wordCount := map[string]map[string]int{}
+	totalWords := map[string]int{}
+	vocabulary := map[string]struct{}{}
+
+	totalDocs := 0
+	for _, e := range examples {
+		var exampleLabels []string
+		for _, l := range labels {
+			if slices.Contains(e.Labels, l) {
+				exampleLabels = append(exampleLabels, l)
+			}
+		}
+
+		if len(exampleLabels) == 0 {
+			continue
+		}
+
+		counts := tokenCounts(e.Title + "\n" + e.Body)
+		for _, l := range exampleLabels {
+			totalDocs++
+			docCount[l]++
+
+			if wordCount[l] == nil {
+				wordCount[l] = map[string]int{}
+			}
+
+			for word, c := range counts {
+				wordCount[l][word] += c
+				totalWords[l] += c
+				vocabulary[word] = struct{}{}
+			}
+		}
+	}
+
+	if totalDocs == 0 {
+		return nil, errors.New("corpus contains no examples for any of the configured labels")
+	}
+
+	m := &bayesModel{
+		logPriors:      map[string]float64{},
+		logLikelihoods: map[string]map[string]float64{},
+		logUnknown:     map[string]float64{},
+		vocabulary:     vocabulary,
+	}
+
+	for _, l := range labels {
+		// labels without any example get laplace smoothed priors, so they can still be chosen if blended with regex scores
+		m.logPriors[l] = math.Log((float64(docCount[l]) + 1) / (float64(totalDocs) + float64(len(labels))))
+
+		denominator := float64(totalWords[l] + len(vocabulary))
+		m.logUnknown[l] = math.Log(1 / denominator)
+		m.logLikelihoods[l] = map[string]float64{}
+		for word, c := range wordCount[l] {
+			m.logLikelihoods[l][word] = math.Log((float64(c) + 1) / denominator)
+		}
+	}
+
+	return m, nil
+}
+
+// posteriors returns the probability of every label given the words of title and body.
+func (m *bayesModel) posteriors(title, body string) map[string]float64 {
+	counts := tokenCounts(title + "\n" + body)
+
+	logPosteriors := make(map[string]float64, len(m.logPriors))
+	maxLog := math.Inf(-1)
+	for l, prior := range m.logPriors {
+		p := prior
+		for word, c := range counts {
+			// words which never appeared in the corpus carry no information
+			if _, ok := m.vocabulary[word]; !ok {
+				continue
+			}
+
+			likelihood, ok := m.logLikelihoods[l][word]
+			if !ok {
+				likelihood = m.logUnknown[l]
+			}
+			p += float64(c) * likelihood
+		}
+
+		logPosteriors[l] = p
+		maxLog = math.Max(maxLog, p)
+	}
+
+	// normalize via log-sum-exp to avoid underflows
+	sum := 0.0
+	for _, p := range logPosteriors {
+		sum += math.Exp(p - maxLog)
+	}
+
+	result := make(map[string]float64, len(logPosteriors))
+	for l, p := range logPosteriors {
+		result[l] = math.Exp(p-maxLog) / sum
+	}
+
+	return result
+}
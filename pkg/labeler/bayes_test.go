@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/stretchr/testify/require"
+)
+
+var bayesCorpus = []Example{
+	{Title: "querier crashes", Body: "query frontend returns errors", Labels: []string{"query"}},
+	{Title: "slow queries", Body: "the querier takes minutes", Labels: []string{"query"}},
+	{Title: "ingester crashes", Body: "out-of-order samples rejected", Labels: []string{"ingest"}},
+	{Title: "distributor drops samples", Body: "ingester is overloaded", Labels: []string{"ingest"}},
+	{Title: "docs typo", Labels: []string{"docs"}}, // not configured, ignored
+}
+
+func TestBayesModel_Posteriors(t *testing.T) {
+	m, err := trainBayesModel(bayesCorpus, []string{"query", "ingest"})
+	require.NoError(t, err)
+
+	p := m.posteriors("querier is slow", "")
+	require.InDelta(t, 1, p["query"]+p["ingest"], 0.0001)
+	require.Greater(t, p["query"], 0.8)
+
+	p = m.posteriors("samples missing", "ingester restarted")
+	require.Greater(t, p["ingest"], 0.8)
+
+	// unknown words carry no information, so the priors are returned
+	p = m.posteriors("completely unrelated", "")
+	require.InDelta(t, 0.5, p["query"], 0.0001)
+}
+
+func TestBayesModel_NoExamples(t *testing.T) {
+	_, err := trainBayesModel(bayesCorpus, []string{"unknown"})
+	require.Error(t, err)
+}
+
+func TestFindLabel_Bayes(t *testing.T) {
+	cfg := Config{
+		Engine: EngineBayes,
+		Bayes:  &BayesConfig{Corpus: "corpus.jsonl"},
+		Labels: map[string]Label{
+			"query":  {Matchers: []Matcher{{RegexStr: `ingest`, regex: regexp.MustCompile(`ingest`), Weight: 1}}},
+			"ingest": {},
+		},
+	}
+
+	l := &Labeler{cfg: cfg, logger: log.NewNopLogger()}
+	_, err := l.findLabel(issue.Issue{Title: "querier is slow"})
+	require.ErrorContains(t, err, "no model has been trained")
+
+	require.NoError(t, cfg.Train(bayesCorpus))
+
+	label, err := l.findLabel(issue.Issue{Title: "querier is slow"})
+	require.NoError(t, err)
+	require.Equal(t, "query", label)
+
+	// regex scores outweigh the bayes probability if blended strongly enough
+	cfg.Bayes.RegexWeight = 0.9
+	label, err = l.findLabel(issue.Issue{Title: "ingester is slow"})
+	require.NoError(t, err)
+	require.Equal(t, "query", label)
+
+	cfg.Bayes.RegexWeight = 0
+	cfg.Bayes.MinScore = 0.99
+	_, err = l.findLabel(issue.Issue{Title: "querier is slow"})
+	require.ErrorContains(t, err, "no label found")
+}
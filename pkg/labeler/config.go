@@ -17,35 +17,131 @@
 package labeler
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"strings"
 
+	"github.com/grafana/escalation-scheduler/pkg/glob"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+	"github.com/grafana/escalation-scheduler/pkg/rules"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	// EngineRegex scores labels by the weights of their matching regular expressions.
+	EngineRegex = "regex"
+
+	// EngineBayes scores labels by a naive bayes model trained from a corpus of labeled issues.
+	EngineBayes = "bayes"
+)
+
 type Config struct {
 	// Labels is a map of label names to matchers based on which the label gets a matching score.
 	// The label with the highest score is applied to the issue.
 	Labels map[string]Label `yaml:"labels,omitempty"`
 
-	// RequireLabel is a list of labels, for the regex-labeler to run at least one of the specified labels must be present on the issue.
-	RequireLabel []string `yaml:"requireLabel,omitempty"`
+	// RequireLabel is a label expression the issue needs to match for the regex-labeler to run.
+	// The legacy list form requires at least one of the listed labels. If empty, the regex-labeler runs on every issue.
+	RequireLabel labelexpr.Expr `yaml:"requireLabel,omitempty"`
+
+	// Engine selects how labels are scored, either "regex" (default) or "bayes".
+	Engine string `yaml:"engine,omitempty"`
+
+	// Bayes configures the naive bayes engine. Required if Engine is set to "bayes".
+	Bayes *BayesConfig `yaml:"bayes,omitempty"`
+
+	// Rules defines named rules which can be referenced by Rule and by individual labels.
+	Rules rules.Set `yaml:"rules,omitempty"`
+
+	// Rule is the name of a rule the issue needs to match for the regex-labeler to run.
+	Rule string `yaml:"rule,omitempty"`
 }
 
 func (c *Config) Validate() error {
+	switch c.Engine {
+	case "", EngineRegex:
+	case EngineBayes:
+		if c.Bayes == nil || c.Bayes.Corpus == "" {
+			return errors.New("engine bayes requires bayes.corpus to be set")
+		}
+		if c.Bayes.RegexWeight < 0 || c.Bayes.RegexWeight > 1 {
+			return fmt.Errorf("bayes.regexWeight must be between 0 and 1, but got %v", c.Bayes.RegexWeight)
+		}
+	default:
+		return fmt.Errorf("unknown engine %q, expected %q or %q", c.Engine, EngineRegex, EngineBayes)
+	}
+
+	if err := c.Rules.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Rules.CheckRef(c.Rule); err != nil {
+		return err
+	}
+
 	for lKey, l := range c.Labels {
 		if err := l.validate(); err != nil {
 			return err
 		}
 
+		if err := c.Rules.CheckRef(l.Rule); err != nil {
+			return fmt.Errorf("invalid label %q: %w", lKey, err)
+		}
+
 		// the call to validate() can modify the label, so we need to put the result back.
 		c.Labels[lKey] = l
 	}
 	return nil
 }
 
+// Train trains the naive bayes model from the given corpus. It needs to be called before labeling issues with the bayes engine.
+func (c *Config) Train(corpus []Example) error {
+	if c.Bayes == nil {
+		return errors.New("bayes engine is not configured")
+	}
+
+	labels := make([]string, 0, len(c.Labels))
+	for l := range c.Labels {
+		labels = append(labels, l)
+	}
+
+	model, err := trainBayesModel(corpus, labels)
+	if err != nil {
+		return fmt.Errorf("unable to train bayes model, due %w", err)
+	}
+
+	c.Bayes.model = model
+	return nil
+}
+
+// BayesConfig configures the naive bayes engine.
+type BayesConfig struct {
+	// Corpus is the path to a JSONL file in the repository containing labeled issues, one github issue per line.
+	Corpus string `yaml:"corpus,omitempty"`
+
+	// RegexWeight blends regex scores into the bayes scores. The final score of a label is
+	// (1-RegexWeight) * bayes probability + RegexWeight * share of the label in the sum of all regex scores.
+	// Defaults to 0, which only uses the bayes probability.
+	RegexWeight float64 `yaml:"regexWeight,omitempty"`
+
+	// MinScore is the score a label needs to reach to be assigned. Defaults to 0, which always assigns the best label.
+	MinScore float64 `yaml:"minScore,omitempty"`
+
+	// model is the trained model, see Config.Train
+	model *bayesModel `yaml:"-"`
+}
+
 type Label struct {
 	// Matchers is a list of regular expressions to match against the title and body of an issue.
 	Matchers []Matcher `yaml:"matchers,omitempty"`
+
+	// RemoveLabels is a list of labels which are removed from the issue once this label is assigned, e.g. `needs-triage`.
+	RemoveLabels []string `yaml:"removeLabels,omitempty"`
+
+	// Rule is the name of a rule the issue needs to match for this label to be considered.
+	Rule string `yaml:"rule,omitempty"`
 }
 
 func (l *Label) validate() error {
@@ -62,6 +158,10 @@ type Matcher struct {
 	// RegexStr is the regular expression to match against the title and body of an issue.
 	RegexStr string `yaml:"regex,omitempty"`
 
+	// Paths is a list of glob patterns to match against the changed files of a pull request.
+	// A matcher can either have a regular expression or paths.
+	Paths []string `yaml:"paths,omitempty"`
+
 	// regex is the compiled version of RegexStr
 	regex *regexp.Regexp `yaml:"-"`
 
@@ -74,11 +174,62 @@ func (m *Matcher) validate() (err error) {
 	if m.Weight == 0 {
 		m.Weight = 1
 	}
+
+	if len(m.Paths) > 0 {
+		if m.RegexStr != "" {
+			return fmt.Errorf("matcher with regex %q can't have paths as well", m.RegexStr)
+		}
+
+		for _, p := range m.Paths {
+			if err := glob.Validate(p); err != nil {
+				return fmt.Errorf("invalid path %q: %w", p, err)
+			}
+		}
+		return nil
+	}
+
 	m.regex, err = regexp.Compile(m.RegexStr)
 	return err
 }
 
+// String describes the matcher, used to report which matchers matched.
+func (m Matcher) String() string {
+	if len(m.Paths) > 0 {
+		return "paths: " + strings.Join(m.Paths, ", ")
+	}
+	return m.RegexStr
+}
+
+// matches reports whether the matcher matches the issue. Path matchers only match pull requests, as only they have changed files.
+func (m Matcher) matches(iss issue.Issue) bool {
+	if len(m.Paths) > 0 {
+		for _, f := range iss.ChangedFiles {
+			for _, p := range m.Paths {
+				if glob.Match(p, f) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return m.regex.MatchString(iss.Title) || m.regex.MatchString(iss.Body)
+}
+
+// UsesPaths reports whether any matcher matches against changed files, which need to be fetched before labeling a pull request.
+func (c *Config) UsesPaths() bool {
+	for _, l := range c.Labels {
+		for _, m := range l.Matchers {
+			if len(m.Paths) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseConfig parses a config. Unknown fields are rejected, as they are usually typos which would be ignored silently.
 func ParseConfig(cfg []byte) (cfgParsed Config, err error) {
-	err = yaml.Unmarshal(cfg, &cfgParsed)
+	err = yaml.UnmarshalStrict(cfg, &cfgParsed)
 	return cfgParsed, err
 }
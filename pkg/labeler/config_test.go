@@ -19,6 +19,7 @@ package labeler
 import (
 	"testing"
 
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,7 +39,7 @@ labels:
 `)
 	cfg, err := ParseConfig(raw)
 	require.NoError(t, err)
-	require.Equal(t, []string{"escalation"}, cfg.RequireLabel)
+	require.Equal(t, labelexpr.AnyOf("escalation"), cfg.RequireLabel)
 	require.Len(t, cfg.Labels, 2)
 
 	squadA, ok := cfg.Labels["squad-a"]
@@ -95,3 +96,53 @@ func TestConfig_Validate_InvalidRegexReturnsError(t *testing.T) {
 
 	require.Error(t, cfg.Validate())
 }
+
+func TestConfig_Validate_Engine(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         Config
+		expectedErr string
+	}{
+		{name: "default engine", cfg: Config{}},
+		{name: "regex engine", cfg: Config{Engine: EngineRegex}},
+		{name: "bayes engine", cfg: Config{Engine: EngineBayes, Bayes: &BayesConfig{Corpus: "corpus.jsonl", RegexWeight: 0.5}}},
+		{name: "bayes engine without corpus", cfg: Config{Engine: EngineBayes}, expectedErr: "bayes.corpus"},
+		{name: "bayes engine with invalid weight", cfg: Config{Engine: EngineBayes, Bayes: &BayesConfig{Corpus: "corpus.jsonl", RegexWeight: 2}}, expectedErr: "regexWeight"},
+		{name: "unknown engine", cfg: Config{Engine: "magic"}, expectedErr: "unknown engine"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestConfig_Validate_UnknownRule(t *testing.T) {
+	cfg := Config{Rule: "unknown"}
+	require.ErrorContains(t, cfg.Validate(), "unknown")
+
+	cfg = Config{Labels: map[string]Label{"label": {Rule: "unknown"}}}
+	require.ErrorContains(t, cfg.Validate(), "unknown")
+}
+
+func TestConfig_Validate_Paths(t *testing.T) {
+	cfg := Config{
+		Labels: map[string]Label{
+			"label": {
+				Matchers: []Matcher{
+					{RegexStr: `.*query.*`, Paths: []string{"pkg/**"}},
+				},
+			},
+		},
+	}
+	require.Error(t, cfg.Validate(), "expected matchers with regex and paths to be rejected")
+
+	cfg.Labels["label"] = Label{Matchers: []Matcher{{Paths: []string{"pkg/[a-"}}}}
+	require.Error(t, cfg.Validate(), "expected invalid globs to be rejected")
+}
@@ -17,57 +17,114 @@
 package labeler
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/google/go-github/github"
 	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
 )
 
-type labelAssigner func(repoOwner, repoName string, issueNumber int, labels []string) error
+// labelAssigner adds the labels in add to an issue and removes the labels in remove afterwards.
+// Other labels of the issue are left untouched.
+type labelAssigner func(repoOwner, repoName string, issueNumber int, add, remove []string) error
 
 type Labeler struct {
 	cfg           Config
 	labelAssigner labelAssigner
 	logger        log.Logger
+	dryRun        bool
 }
 
-func NewLabeler(cfg Config, gh *github.Client, logger log.Logger) *Labeler {
+// NewLabeler creates a Labeler which assigns labels through the given github client.
+// If dryRun is set, the chosen label is only logged and set as output, but never applied to the issue.
+func NewLabeler(cfg Config, gh *github.Client, dryRun bool, logger log.Logger) *Labeler {
+	assigner := getLabelAssigner(gh)
+	if dryRun {
+		assigner = getDryRunLabelAssigner(logger)
+	}
+
 	return &Labeler{
 		cfg:           cfg,
-		labelAssigner: getLabelAssigner(gh),
+		labelAssigner: assigner,
 		logger:        logger,
+		dryRun:        dryRun,
 	}
 }
 
-func (l *Labeler) Run(issue *github.Issue) error {
-	if !l.hasRequiredLabels(issue) {
-		level.Info(l.logger).Log("msg", "issue has none of the required labels", "requireLabel", strings.Join(l.cfg.RequireLabel, ", "))
+// LabelScore is the score a single label achieved when evaluating its matchers against an issue.
+type LabelScore struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+
+	// Matched contains the regular expressions of all matchers which matched.
+	Matched []string `json:"matched,omitempty"`
+}
+
+// Run assigns a label to the issue, unless it already has one of the assignable labels.
+func (l *Labeler) Run(iss issue.Issue) error {
+	return l.run(iss, false)
+}
+
+// Retriage recomputes the label of the issue, even if it already has one of the assignable labels.
+// If the chosen label differs, all other assignable labels are removed from the issue.
+func (l *Labeler) Retriage(iss issue.Issue) error {
+	return l.run(iss, true)
+}
+
+func (l *Labeler) run(iss issue.Issue, retriage bool) error {
+	if !l.hasRequiredLabels(iss) {
+		level.Info(l.logger).Log("msg", "issue doesn't match the required labels", "requireLabel", l.cfg.RequireLabel.String())
+		l.skip(iss, "the issue doesn't match the required labels")
 		return nil
 	}
 
-	level.Info(l.logger).Log("msg", "issue has at least one of the required labels", "requireLabel", strings.Join(l.cfg.RequireLabel, ", "))
+	level.Info(l.logger).Log("msg", "issue matches the required labels", "requireLabel", l.cfg.RequireLabel.String())
 
-	if l.hasAssignableLabel(issue) {
+	if !l.cfg.Rules.Match(l.cfg.Rule, iss, time.Now()) {
+		level.Info(l.logger).Log("msg", "issue doesn't match the required rule", "rule", l.cfg.Rule)
+		l.skip(iss, "the issue doesn't match the required rule")
 		return nil
 	}
 
-	level.Info(l.logger).Log("msg", "issue does not have one of the assignable labels", "assignable_labels", strings.Join(l.getAssignableLabels(), ", "))
+	if !retriage {
+		if l.hasAssignableLabel(iss) {
+			l.skip(iss, "the issue already has one of the assignable labels")
+			return nil
+		}
+
+		level.Info(l.logger).Log("msg", "issue does not have one of the assignable labels", "assignable_labels", strings.Join(l.getAssignableLabels(), ", "))
+	}
 
-	label, err := l.findLabel(issue.GetTitle(), issue.GetBody())
+	label, scores, err := l.FindLabel(iss)
+	l.writeSummary(summaryMarkdown(iss.Number, scores, label, l.dryRun))
 	if err != nil {
 		return err
 	}
 
-	err = l.assignLabel(issue, label)
+	err = l.assignLabel(iss, label, retriage)
 	if err != nil {
 		return err
 	}
 
-	err = githubaction.SetOutput("assignedLabel", label)
+	scoresJSON, err := json.Marshal(scores)
+	if err != nil {
+		return fmt.Errorf("unable to encode scores, due %w", err)
+	}
+
+	err = githubaction.SetOutputs(map[string]string{
+		"assignedLabel":  label,
+		"label":          label,
+		"scores":         string(scoresJSON),
+		"skipped-reason": "",
+	})
 	if err != nil {
 		return err
 	}
@@ -77,65 +134,130 @@ func (l *Labeler) Run(issue *github.Issue) error {
 	return nil
 }
 
-func (l *Labeler) findLabel(title, body string) (label string, err error) {
+// Scores evaluates the matchers of all configured labels against title and body of the issue and the changed files of pull requests.
+// If the bayes engine is configured, the scores are the (blended) bayes probabilities instead.
+// The result contains every configured label whose rule matches the issue and is sorted by descending score,
+// ties are broken by label name.
+func (l *Labeler) Scores(iss issue.Issue) []LabelScore {
 	// Don't log title / body because they might contain sensitive data.
+	title, body := iss.Title, iss.Body
+	now := time.Now()
 
-	scoreByLabel := make(map[string]int)
+	scores := make([]LabelScore, 0, len(l.cfg.Labels))
 	for label, properties := range l.cfg.Labels {
-		level.Info(l.logger).Log("msg", "evaluating regular expressions for label", "label", label)
+		if !l.cfg.Rules.Match(properties.Rule, iss, now) {
+			level.Debug(l.logger).Log("msg", "skipping label as the issue doesn't match its rule", "label", label, "rule", properties.Rule)
+			continue
+		}
+
+		level.Debug(l.logger).Log("msg", "evaluating regular expressions for label", "label", label)
 
+		score := LabelScore{Label: label}
 		for _, matcher := range properties.Matchers {
-			if matcher.regex.MatchString(title) || matcher.regex.MatchString(body) {
-				level.Info(l.logger).Log("msg", "regex matches", "regex", matcher.RegexStr, "weight", matcher.Weight)
-				scoreByLabel[label] += matcher.Weight
+			if matcher.matches(iss) {
+				level.Debug(l.logger).Log("msg", "matcher matches", "matcher", matcher.String(), "weight", matcher.Weight)
+				score.Score += float64(matcher.Weight)
+				score.Matched = append(score.Matched, matcher.String())
 			} else {
-				level.Info(l.logger).Log("msg", "regex does not match", "regex", matcher.RegexStr)
+				level.Debug(l.logger).Log("msg", "matcher does not match", "matcher", matcher.String())
 			}
 		}
+
+		scores = append(scores, score)
 	}
 
-	var bestLabel string
-	for label, score := range scoreByLabel {
-		level.Info(l.logger).Log("msg", "label has score assigned", "label", label, "score", score)
-		if bestLabel == "" {
-			bestLabel = label
-			continue
+	if l.cfg.Engine == EngineBayes && l.cfg.Bayes.model != nil {
+		l.blendBayesScores(scores, title, body)
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
 		}
+		return scores[i].Label < scores[j].Label
+	})
+
+	return scores
+}
 
-		if score > scoreByLabel[bestLabel] {
-			bestLabel = label
+// blendBayesScores replaces the regex scores by the bayes probabilities, blended with the share of the regex scores if configured.
+func (l *Labeler) blendBayesScores(scores []LabelScore, title, body string) {
+	posteriors := l.cfg.Bayes.model.posteriors(title, body)
+
+	regexSum := 0.0
+	for _, s := range scores {
+		regexSum += s.Score
+	}
+
+	w := l.cfg.Bayes.RegexWeight
+	for i, s := range scores {
+		regexShare := 0.0
+		if regexSum > 0 {
+			regexShare = s.Score / regexSum
 		}
+
+		level.Debug(l.logger).Log("msg", "bayes probability of label", "label", s.Label, "probability", posteriors[s.Label], "regexShare", regexShare)
+		scores[i].Score = (1-w)*posteriors[s.Label] + w*regexShare
 	}
+}
+
+func (l *Labeler) findLabel(iss issue.Issue) (label string, err error) {
+	label, _, err = l.FindLabel(iss)
+	return label, err
+}
 
-	if scoreByLabel[bestLabel] == 0 {
-		return "", errors.New("no label found")
+// FindLabel returns the label which would be assigned to the issue along with the scores of all labels.
+// An error is returned if no label reaches the required score.
+func (l *Labeler) FindLabel(iss issue.Issue) (string, []LabelScore, error) {
+	if l.cfg.Engine == EngineBayes && (l.cfg.Bayes == nil || l.cfg.Bayes.model == nil) {
+		return "", nil, errors.New("bayes engine is configured, but no model has been trained")
 	}
 
-	level.Info(l.logger).Log("msg", "label has been chosen", "label", bestLabel, "score", scoreByLabel[bestLabel])
+	scores := l.Scores(iss)
+	for _, s := range scores {
+		level.Info(l.logger).Log("msg", "label has score assigned", "label", s.Label, "score", s.Score)
+	}
+
+	minScore := 0.0
+	if l.cfg.Engine == EngineBayes {
+		minScore = l.cfg.Bayes.MinScore
+	}
+
+	if len(scores) == 0 || scores[0].Score == 0 {
+		return "", scores, errors.New("no label found")
+	}
+	if scores[0].Score < minScore {
+		return "", scores, fmt.Errorf("no label found, the best score %.4g is below the minimum score %.4g", scores[0].Score, minScore)
+	}
 
-	return bestLabel, nil
+	level.Info(l.logger).Log("msg", "label has been chosen", "label", scores[0].Label, "score", scores[0].Score)
+
+	return scores[0].Label, scores, nil
 }
 
-func (l *Labeler) assignLabel(issue *github.Issue, label string) error {
+// assignLabel adds the label to the issue. If replace is set, all other assignable labels of the issue are removed.
+func (l *Labeler) assignLabel(iss issue.Issue, label string, replace bool) error {
 	level.Info(l.logger).Log("msg", "assigning label to issue", "label", label)
+	level.Info(l.logger).Log("msg", "issue currently has labels", "labels", strings.Join(iss.Labels, ", "))
 
-	issueLabels := getIssueLabels(issue)
-	level.Info(l.logger).Log("msg", "issue currently has labels", "labels", strings.Join(issueLabels, ", "))
-
-	for _, currentLabel := range issueLabels {
-		if currentLabel == label {
-			level.Info(l.logger).Log("msg", "issue already has the label", "label", label)
-			// Label already assigned
-			return nil
+	removeLabels := slices.Clone(l.cfg.Labels[label].RemoveLabels)
+	if replace {
+		for _, currentLabel := range iss.Labels {
+			if _, ok := l.cfg.Labels[currentLabel]; ok && currentLabel != label && !slices.Contains(removeLabels, currentLabel) {
+				removeLabels = append(removeLabels, currentLabel)
+			}
 		}
 	}
 
-	issueLabels = append(issueLabels, label)
+	if slices.Contains(iss.Labels, label) && !slices.ContainsFunc(iss.Labels, func(currentLabel string) bool { return slices.Contains(removeLabels, currentLabel) }) {
+		level.Info(l.logger).Log("msg", "issue already has the label", "label", label)
+		// Label already assigned and nothing to remove
+		return nil
+	}
 
-	level.Info(l.logger).Log("msg", "issue is going to have labels", "labels", strings.Join(issueLabels, ", "))
+	level.Info(l.logger).Log("msg", "changing labels of issue", "add", label, "remove", strings.Join(removeLabels, ", "))
 
-	repoOwner, repoName := decomposeRepoURL(*issue.RepositoryURL)
-	return l.labelAssigner(repoOwner, repoName, issue.GetNumber(), issueLabels)
+	return l.labelAssigner(iss.Owner, iss.Repo, iss.Number, []string{label}, removeLabels)
 }
 
 func (l *Labeler) getAssignableLabels() []string {
@@ -146,11 +268,9 @@ func (l *Labeler) getAssignableLabels() []string {
 	return assignableLabels
 }
 
-func (l *Labeler) hasAssignableLabel(issue *github.Issue) bool {
-	issueLabels := getIssueLabels(issue)
-
+func (l *Labeler) hasAssignableLabel(iss issue.Issue) bool {
 	for _, assignableLabel := range l.getAssignableLabels() {
-		if slices.Contains[[]string, string](issueLabels, assignableLabel) {
+		if slices.Contains[[]string, string](iss.Labels, assignableLabel) {
 			level.Info(l.logger).Log("msg", "issue already has at least one of the assignable labels, aborting run", "label", assignableLabel)
 			return true
 		}
@@ -159,16 +279,6 @@ func (l *Labeler) hasAssignableLabel(issue *github.Issue) bool {
 	return false
 }
 
-func (l *Labeler) hasRequiredLabels(issue *github.Issue) bool {
-	issueLabels := getIssueLabels(issue)
-
-	match := false
-	for _, requiredLabel := range l.cfg.RequireLabel {
-		if slices.Contains[[]string, string](issueLabels, requiredLabel) {
-			match = true
-		}
-
-	}
-
-	return match
+func (l *Labeler) hasRequiredLabels(iss issue.Issue) bool {
+	return l.cfg.RequireLabel.Match(iss.Labels)
 }
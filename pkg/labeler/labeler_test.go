@@ -18,11 +18,14 @@ package labeler
 
 import (
 	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 
 	"github.com/go-kit/log"
-	"github.com/google/go-github/github"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+	"github.com/grafana/escalation-scheduler/pkg/rules"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,12 +43,11 @@ func TestAssigningLabel(t *testing.T) {
 	testIssueNumber := 333
 	testRepoOwner := "testOwner"
 	testRepoName := "testRepo"
-	testRepositoreURL := "/repos/" + testRepoOwner + "/" + testRepoName
 
 	type testCase struct {
 		name                       string
 		cfg                        Config
-		issue                      *github.Issue
+		issue                      issue.Issue
 		expectedLabelAssignerCalls []labelAssignerCall
 	}
 
@@ -53,7 +55,7 @@ func TestAssigningLabel(t *testing.T) {
 		{
 			name: "assign the mimir-query label",
 			cfg: Config{
-				RequireLabel: []string{"label1", "label3"},
+				RequireLabel: labelexpr.AnyOf("label1", "label3"),
 				Labels: map[string]Label{
 					"mimir-ingest": {
 						Matchers: []Matcher{
@@ -70,29 +72,32 @@ func TestAssigningLabel(t *testing.T) {
 								Weight: 1,
 							},
 						},
+						RemoveLabels: []string{"needs-triage"},
 					},
 				},
 			},
-			issue: &github.Issue{
-				Number:        &testIssueNumber,
-				Title:         github.String("some title"),
-				Body:          github.String("some body abc something something query more text."),
-				RepositoryURL: &testRepositoreURL,
-				Labels: []github.Label{
-					{Name: github.String("label1")},
-					{Name: github.String("label2")},
+			issue: issue.Issue{
+				Number: testIssueNumber,
+				Title:  "some title",
+				Body:   "some body abc something something query more text.",
+				Owner:  testRepoOwner,
+				Repo:   testRepoName,
+				Labels: []string{
+					"label1",
+					"label2",
 				},
 			},
 			expectedLabelAssignerCalls: []labelAssignerCall{{
 				repoOwner:   testRepoOwner,
 				repoName:    testRepoName,
 				issueNumber: testIssueNumber,
-				labels:      []string{"label1", "label2", "mimir-query"},
+				add:         []string{"mimir-query"},
+				remove:      []string{"needs-triage"},
 			}},
 		}, {
 			name: "don't assign label due to lack of required labels",
 			cfg: Config{
-				RequireLabel: []string{"label1", "label2"},
+				RequireLabel: labelexpr.AnyOf("label1", "label2"),
 				Labels: map[string]Label{
 					"mimir-query": {
 						Matchers: []Matcher{
@@ -104,14 +109,15 @@ func TestAssigningLabel(t *testing.T) {
 					},
 				},
 			},
-			issue: &github.Issue{
-				Number:        &testIssueNumber,
-				Title:         github.String("some title"),
-				Body:          github.String("some body abc something something query more text."),
-				RepositoryURL: &testRepositoreURL,
-				Labels: []github.Label{
-					{Name: github.String("label3")},
-					{Name: github.String("label4")},
+			issue: issue.Issue{
+				Number: testIssueNumber,
+				Title:  "some title",
+				Body:   "some body abc something something query more text.",
+				Owner:  testRepoOwner,
+				Repo:   testRepoName,
+				Labels: []string{
+					"label3",
+					"label4",
 				},
 			},
 			expectedLabelAssignerCalls: nil,
@@ -134,14 +140,38 @@ func TestAssigningLabel(t *testing.T) {
 	}
 }
 
+func TestAssigningLabel_WithoutRequireLabel(t *testing.T) {
+	setGithubOutput(t)
+
+	testIssueNumber := 333
+	testRepoOwner := "testOwner"
+	testRepoName := "testRepo"
+
+	cfg := Config{
+		Labels: map[string]Label{
+			"target-label": {Matchers: []Matcher{{regex: regexp.MustCompile(`.*`), Weight: 1}}},
+		},
+	}
+	iss := issue.Issue{
+		Number: testIssueNumber,
+		Title:  "some title",
+		Owner:  testRepoOwner,
+		Repo:   testRepoName,
+	}
+
+	mockLabelAssigner, calls := getMockLabelAssigner()
+	l := &Labeler{cfg: cfg, labelAssigner: mockLabelAssigner, logger: log.NewNopLogger()}
+	require.NoError(t, l.Run(iss))
+	require.Len(t, *calls, 1, "expected issues to be labeled if no labels are required")
+}
+
 func TestAssigningLabel_AlreadyHasAssignableLabel(t *testing.T) {
 	testIssueNumber := 333
 	testRepoOwner := "testOwner"
 	testRepoName := "testRepo"
-	testRepositoryURL := "/repos/" + testRepoOwner + "/" + testRepoName
 
 	cfg := Config{
-		RequireLabel: []string{"required"},
+		RequireLabel: labelexpr.Label("required"),
 		Labels: map[string]Label{
 			"target-label": {
 				Matchers: []Matcher{
@@ -150,33 +180,125 @@ func TestAssigningLabel_AlreadyHasAssignableLabel(t *testing.T) {
 			},
 		},
 	}
-	issue := &github.Issue{
-		Number:        &testIssueNumber,
-		Title:         github.String("some title"),
-		Body:          github.String("some body"),
-		RepositoryURL: &testRepositoryURL,
-		Labels: []github.Label{
-			{Name: github.String("required")},
-			{Name: github.String("target-label")}, // already has the assignable label
+	iss := issue.Issue{
+		Number: testIssueNumber,
+		Title:  "some title",
+		Body:   "some body",
+		Owner:  testRepoOwner,
+		Repo:   testRepoName,
+		Labels: []string{
+			"required",
+			"target-label", // already has the assignable label
 		},
 	}
 
 	mockLabelAssigner, calls := getMockLabelAssigner()
 	l := &Labeler{cfg: cfg, labelAssigner: mockLabelAssigner, logger: log.NewNopLogger()}
-	require.NoError(t, l.Run(issue))
+	require.NoError(t, l.Run(iss))
 	require.Nil(t, *calls, "expected no label assigner calls when issue already has an assignable label")
 }
 
+func TestRetriage_ReplacesOutdatedLabel(t *testing.T) {
+	setGithubOutput(t)
+
+	cfg := Config{
+		Labels: map[string]Label{
+			"mimir-query": {
+				Matchers: []Matcher{
+					{regex: regexp.MustCompile(`.*query.*`), Weight: 1},
+				},
+			},
+			"mimir-ingest": {
+				Matchers: []Matcher{
+					{regex: regexp.MustCompile(`.*ingest.*`), Weight: 1},
+				},
+			},
+		},
+	}
+	iss := issue.Issue{
+		Number: 333,
+		Title:  "a query title",
+		Owner:  "testOwner",
+		Repo:   "testRepo",
+		Labels: []string{"mimir-ingest", "unrelated"},
+	}
+
+	mockLabelAssigner, calls := getMockLabelAssigner()
+	l := &Labeler{cfg: cfg, labelAssigner: mockLabelAssigner, logger: log.NewNopLogger()}
+
+	require.NoError(t, l.Run(iss))
+	require.Nil(t, *calls, "expected Run to keep the existing assignable label")
+
+	require.NoError(t, l.Retriage(iss))
+	require.Len(t, *calls, 1)
+	require.Equal(t, []string{"mimir-query"}, (*calls)[0].add)
+	require.Equal(t, []string{"mimir-ingest"}, (*calls)[0].remove)
+}
+
+func TestRetriage_KeepsMatchingLabel(t *testing.T) {
+	cfg := Config{
+		Labels: map[string]Label{
+			"mimir-query": {
+				Matchers: []Matcher{
+					{regex: regexp.MustCompile(`.*query.*`), Weight: 1},
+				},
+			},
+		},
+	}
+	iss := issue.Issue{
+		Number: 333,
+		Title:  "a query title",
+		Owner:  "testOwner",
+		Repo:   "testRepo",
+		Labels: []string{"mimir-query"},
+	}
+
+	setGithubOutput(t)
+
+	mockLabelAssigner, calls := getMockLabelAssigner()
+	l := &Labeler{cfg: cfg, labelAssigner: mockLabelAssigner, logger: log.NewNopLogger()}
+	require.NoError(t, l.Retriage(iss))
+	require.Nil(t, *calls, "expected no changes if the label is still the best one")
+}
+
+func TestRetriage_RemovesLabelsWhenLabelIsKept(t *testing.T) {
+	cfg := Config{
+		Labels: map[string]Label{
+			"mimir-query": {
+				Matchers: []Matcher{
+					{regex: regexp.MustCompile(`.*query.*`), Weight: 1},
+				},
+				RemoveLabels: []string{"needs-triage"},
+			},
+		},
+	}
+	iss := issue.Issue{
+		Number: 333,
+		Title:  "a query title",
+		Owner:  "testOwner",
+		Repo:   "testRepo",
+		Labels: []string{"mimir-query", "needs-triage"},
+	}
+
+	setGithubOutput(t)
+
+	mockLabelAssigner, calls := getMockLabelAssigner()
+	l := &Labeler{cfg: cfg, labelAssigner: mockLabelAssigner, logger: log.NewNopLogger()}
+	require.NoError(t, l.Retriage(iss))
+	require.Len(t, *calls, 1, "expected the configured labels to be removed even if the label is already present")
+	require.Equal(t, []string{"mimir-query"}, (*calls)[0].add)
+	require.Equal(t, []string{"needs-triage"}, (*calls)[0].remove)
+}
+
 func TestAssigningLabel_HigherWeightedScoreWins(t *testing.T) {
 	setGithubOutput(t)
 
 	testIssueNumber := 333
 	testRepoOwner := "testOwner"
 	testRepoName := "testRepo"
-	testRepositoryURL := "/repos/" + testRepoOwner + "/" + testRepoName
 
 	cfg := Config{
-		RequireLabel: []string{"required"},
+		RequireLabel: labelexpr.Label("required"),
 		Labels: map[string]Label{
 			"low-priority": {
 				Matchers: []Matcher{
@@ -190,21 +312,52 @@ func TestAssigningLabel_HigherWeightedScoreWins(t *testing.T) {
 			},
 		},
 	}
-	issue := &github.Issue{
-		Number:        &testIssueNumber,
-		Title:         github.String("a query title"),
-		Body:          github.String("some body"),
-		RepositoryURL: &testRepositoryURL,
-		Labels:        []github.Label{{Name: github.String("required")}},
+	iss := issue.Issue{
+		Number: testIssueNumber,
+		Title:  "a query title",
+		Body:   "some body",
+		Owner:  testRepoOwner,
+		Repo:   testRepoName,
+		Labels: []string{"required"},
 	}
 
 	mockLabelAssigner, calls := getMockLabelAssigner()
 	l := &Labeler{cfg: cfg, labelAssigner: mockLabelAssigner, logger: log.NewNopLogger()}
-	require.NoError(t, l.Run(issue))
+	require.NoError(t, l.Run(iss))
 
 	require.Len(t, *calls, 1)
-	require.Contains(t, (*calls)[0].labels, "high-priority")
-	require.NotContains(t, (*calls)[0].labels, "low-priority")
+	require.Equal(t, []string{"high-priority"}, (*calls)[0].add)
+}
+
+func TestScores_PathsCombinedWithRegex(t *testing.T) {
+	cfg := Config{
+		Labels: map[string]Label{
+			"squad-ingest": {
+				Matchers: []Matcher{
+					{Paths: []string{"pkg/ingester/**"}, Weight: 5},
+				},
+			},
+			"squad-query": {
+				Matchers: []Matcher{
+					{RegexStr: "query"},
+					{Paths: []string{"pkg/querier/**"}},
+				},
+			},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	require.True(t, cfg.UsesPaths())
+
+	l := &Labeler{cfg: cfg, logger: log.NewNopLogger()}
+
+	scores := l.Scores(issue.Issue{Title: "improve query", IsPullRequest: true, ChangedFiles: []string{"pkg/querier/querier.go"}})
+	require.Equal(t, "squad-query", scores[0].Label)
+	require.Equal(t, 2.0, scores[0].Score)
+	require.Equal(t, []string{"query", "paths: pkg/querier/**"}, scores[0].Matched)
+
+	scores = l.Scores(issue.Issue{Title: "improve query", IsPullRequest: true, ChangedFiles: []string{"pkg/ingester/ingester.go"}})
+	require.Equal(t, "squad-ingest", scores[0].Label)
+	require.Equal(t, 5.0, scores[0].Score)
 }
 
 func TestFindLabel_NoMatch(t *testing.T) {
@@ -221,25 +374,117 @@ func TestFindLabel_NoMatch(t *testing.T) {
 		logger: log.NewNopLogger(),
 	}
 
-	_, err := l.findLabel("unrelated title", "unrelated body")
+	_, err := l.findLabel(issue.Issue{Title: "unrelated title", Body: "unrelated body"})
 	require.Error(t, err, "expected error when no regex matches")
 }
 
+func TestScores_SortedByScoreAndName(t *testing.T) {
+	l := &Labeler{
+		cfg: Config{
+			Labels: map[string]Label{
+				"b-label": {Matchers: []Matcher{{RegexStr: `query`, regex: regexp.MustCompile(`query`), Weight: 1}}},
+				"a-label": {Matchers: []Matcher{{RegexStr: `query`, regex: regexp.MustCompile(`query`), Weight: 1}}},
+				"c-label": {Matchers: []Matcher{
+					{RegexStr: `slow`, regex: regexp.MustCompile(`slow`), Weight: 3},
+					{RegexStr: `ingest`, regex: regexp.MustCompile(`ingest`), Weight: 1},
+				}},
+				"d-label": {Matchers: []Matcher{{RegexStr: `nothing`, regex: regexp.MustCompile(`nothing`), Weight: 5}}},
+			},
+		},
+		logger: log.NewNopLogger(),
+	}
+
+	scores := l.Scores(issue.Issue{Title: "slow query"})
+	require.Equal(t, []LabelScore{
+		{Label: "c-label", Score: 3, Matched: []string{"slow"}},
+		{Label: "a-label", Score: 1, Matched: []string{"query"}},
+		{Label: "b-label", Score: 1, Matched: []string{"query"}},
+		{Label: "d-label", Score: 0},
+	}, scores)
+}
+
+func TestScores_LabelRule(t *testing.T) {
+	cfg := Config{
+		Rules: rules.Set{
+			"pull-requests": {Type: rules.TypePullRequest},
+		},
+		Labels: map[string]Label{
+			"pr-label":    {Rule: "pull-requests", Matchers: []Matcher{{regex: regexp.MustCompile(`query`), Weight: 5}}},
+			"issue-label": {Matchers: []Matcher{{regex: regexp.MustCompile(`query`), Weight: 1}}},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	l := &Labeler{cfg: cfg, logger: log.NewNopLogger()}
+
+	label, err := l.findLabel(issue.Issue{Title: "query"})
+	require.NoError(t, err)
+	require.Equal(t, "issue-label", label)
+
+	label, err = l.findLabel(issue.Issue{Title: "query", IsPullRequest: true})
+	require.NoError(t, err)
+	require.Equal(t, "pr-label", label)
+}
+
+func TestNewLabeler_DryRunDoesNotAssign(t *testing.T) {
+	setGithubOutput(t)
+
+	testIssueNumber := 333
+	testRepoOwner := "testOwner"
+	testRepoName := "testRepo"
+
+	cfg := Config{
+		RequireLabel: labelexpr.Label("required"),
+		Labels: map[string]Label{
+			"target-label": {Matchers: []Matcher{{RegexStr: `.*`, regex: regexp.MustCompile(`.*`), Weight: 1}}},
+		},
+	}
+	iss := issue.Issue{
+		Number: testIssueNumber,
+		Title:  "some title",
+		Owner:  testRepoOwner,
+		Repo:   testRepoName,
+		Labels: []string{"required"},
+	}
+
+	summaryFile := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summaryFile)
+
+	// the github client is never used in dry-run mode, hence it's fine to pass nil
+	l := NewLabeler(cfg, nil, true, log.NewNopLogger())
+	require.NoError(t, l.Run(iss))
+
+	output, err := os.ReadFile(os.Getenv("GITHUB_OUTPUT"))
+	require.NoError(t, err)
+	require.Contains(t, string(output), "assignedLabel<<ghadelimiter_")
+	require.Contains(t, string(output), "\ntarget-label\nghadelimiter_")
+	require.Contains(t, string(output), "\n"+`[{"label":"target-label","score":1,"matched":[".*"]}]`+"\nghadelimiter_")
+
+	summary, err := os.ReadFile(summaryFile)
+	require.NoError(t, err)
+	require.Equal(t, "### Label of #333\n\n"+
+		"| Label | Score | Matched |\n| --- | --- | --- |\n| target-label | 1 | `.*` |\n\n"+
+		"Chosen label: **target-label**\n\n"+
+		"Dry-run is enabled, the issue hasn't been changed.\n\n", string(summary))
+}
+
 type labelAssignerCall struct {
 	repoOwner   string
 	repoName    string
 	issueNumber int
-	labels      []string
+	add         []string
+	remove      []string
 }
 
 func getMockLabelAssigner() (labelAssigner, *[]labelAssignerCall) {
 	var calls []labelAssignerCall
-	return func(repoOwner, repoName string, issueNumber int, labels []string) error {
+	return func(repoOwner, repoName string, issueNumber int, add, remove []string) error {
 		calls = append(calls, labelAssignerCall{
 			repoOwner:   repoOwner,
 			repoName:    repoName,
 			issueNumber: issueNumber,
-			labels:      labels,
+			add:         add,
+			remove:      remove,
 		})
 		return nil
 	}, &calls
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/grafana/escalation-scheduler/pkg/rules"
+)
+
+// Lint reports parts of a valid config which most likely don't work as intended, e.g. labels which are never assigned.
+// The config needs to be validated before, the findings are sorted.
+func (c *Config) Lint() []string {
+	var findings []string
+
+	rootType := c.Rules[c.Rule].Type
+
+	referenced := map[string]bool{c.Rule: true}
+	for name, l := range c.Labels {
+		referenced[l.Rule] = true
+
+		if len(l.Matchers) == 0 && (c.Engine == "" || c.Engine == EngineRegex) {
+			findings = append(findings, fmt.Sprintf("label %q has no matchers and is never assigned", name))
+			continue
+		}
+
+		labelType := c.Rules[l.Rule].Type
+		if rootType != "" && labelType != "" && rootType != labelType {
+			findings = append(findings, fmt.Sprintf("label %q is never assigned, as its rule %q only matches type %q, but rule %q only matches type %q", name, l.Rule, labelType, c.Rule, rootType))
+			continue
+		}
+
+		if l.onlyPaths() && (rootType == rules.TypeIssue || labelType == rules.TypeIssue) {
+			findings = append(findings, fmt.Sprintf("label %q is never assigned, as it only has path matchers but is restricted to issues", name))
+		}
+	}
+
+	for name := range c.Rules {
+		if !referenced[name] {
+			findings = append(findings, fmt.Sprintf("rule %q is never referenced", name))
+		}
+	}
+
+	sort.Strings(findings)
+	return findings
+}
+
+// onlyPaths reports whether all matchers of the label are path matchers, which never match issues.
+func (l Label) onlyPaths() bool {
+	for _, m := range l.Matchers {
+		if len(m.Paths) == 0 {
+			return false
+		}
+	}
+	return len(l.Matchers) > 0
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Lint(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+rule: issues
+rules:
+  issues:
+    type: issue
+  pull-requests:
+    type: pull_request
+  unused:
+    title: foo
+labels:
+  bug:
+    matchers:
+      - regex: bug
+  empty: {}
+  docs:
+    matchers:
+      - paths: [docs/]
+  reviews:
+    rule: pull-requests
+    matchers:
+      - regex: review
+`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	require.Equal(t, []string{
+		`label "docs" is never assigned, as it only has path matchers but is restricted to issues`,
+		`label "empty" has no matchers and is never assigned`,
+		`label "reviews" is never assigned, as its rule "pull-requests" only matches type "pull_request", but rule "issues" only matches type "issue"`,
+		`rule "unused" is never referenced`,
+	}, cfg.Lint())
+}
+
+func TestParseConfig_RejectsUnknownFields(t *testing.T) {
+	for name, raw := range map[string]string{
+		"root":             "required_labels: [bug]",
+		"label":            "labels: {bug: {matcher: []}}",
+		"matcher":          "labels: {bug: {matchers: [{regexp: bug}]}}",
+		"label expression": "requireLabel: {al: [bug]}",
+		"rule":             "rules: {bugs: {label: bug}}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(raw))
+			require.Error(t, err)
+		})
+	}
+}
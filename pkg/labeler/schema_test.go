@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grafana/escalation-scheduler/pkg/rules"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSchema_CoversConfig ensures the published schema knows every field of the config, as it rejects unknown fields.
+func TestSchema_CoversConfig(t *testing.T) {
+	raw, err := os.ReadFile("../../schemas/regex-labeler.schema.json")
+	require.NoError(t, err)
+
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+
+	definitions := schema["definitions"].(map[string]interface{})
+	for name, tc := range map[string]struct {
+		schema interface{}
+		typ    reflect.Type
+	}{
+		"root":    {schema, reflect.TypeOf(Config{})},
+		"label":   {definitions["label"], reflect.TypeOf(Label{})},
+		"matcher": {definitions["matcher"], reflect.TypeOf(Matcher{})},
+		"bayes":   {definitions["bayes"], reflect.TypeOf(BayesConfig{})},
+		"rule":    {definitions["rule"], reflect.TypeOf(rules.Rule{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := tc.schema.(map[string]interface{})
+			require.Equal(t, false, s["additionalProperties"])
+			require.Equal(t, yamlFields(tc.typ), keys(s["properties"].(map[string]interface{})))
+		})
+	}
+}
+
+func yamlFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func keys(m map[string]interface{}) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
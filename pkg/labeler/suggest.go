@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxSuggestedWeight caps the weight of suggested matchers, so that a single token can't dominate a config.
+	maxSuggestedWeight = 10
+
+	// suggestionPrior is the pseudo count added to token frequencies to smooth the log-odds of rare tokens.
+	suggestionPrior = 0.5
+)
+
+// stopWords are common english words and issue template boilerplate which never make a distinctive matcher.
+var stopWords = map[string]struct{}{
+	"about": {}, "after": {}, "also": {}, "and": {}, "any": {}, "are": {}, "but": {}, "can": {}, "could": {},
+	"did": {}, "does": {}, "expected": {}, "for": {}, "from": {}, "had": {}, "has": {}, "have": {}, "how": {},
+	"http": {}, "https": {}, "into": {}, "its": {}, "not": {}, "one": {}, "our": {}, "should": {}, "that": {},
+	"the": {}, "then": {}, "there": {}, "this": {}, "was": {}, "were": {}, "what": {}, "when": {}, "which": {},
+	"will": {}, "with": {}, "would": {}, "www": {}, "you": {}, "your": {},
+}
+
+// SuggestOptions configures which matchers SuggestMatchers proposes.
+type SuggestOptions struct {
+	// Labels restricts suggestions to the given labels. If empty, matchers are suggested for every label found in the examples.
+	Labels []string
+
+	// MaxMatchers is the maximum amount of matchers suggested per label.
+	MaxMatchers int
+
+	// MinSupport is the minimum amount of examples with a given label a token needs to appear in to be suggested.
+	MinSupport int
+}
+
+// SuggestMatchers proposes matchers for labels based on the titles and bodies of examples carrying them.
+//
+// Tokens are ranked by their smoothed log-odds ratio of appearing in examples with a label compared to examples
+// without it. Every token with a positive ratio becomes a case insensitive word matcher, its weight is derived from the
+// ratio. The returned config is meant as a starting point for a hand-tuned configuration, not to be used as is.
+func SuggestMatchers(examples []Example, opts SuggestOptions) Config {
+	if opts.MaxMatchers <= 0 {
+		opts.MaxMatchers = 5
+	}
+	if opts.MinSupport <= 0 {
+		opts.MinSupport = 2
+	}
+
+	tokensPerExample := make([]map[string]struct{}, len(examples))
+	totalFrequency := map[string]int{}
+	for i, e := range examples {
+		tokensPerExample[i] = tokenize(e.Title + "\n" + e.Body)
+		for token := range tokensPerExample[i] {
+			totalFrequency[token]++
+		}
+	}
+
+	labels := opts.Labels
+	if len(labels) == 0 {
+		labels = exampleLabels(examples)
+	}
+
+	cfg := Config{Labels: map[string]Label{}}
+	for _, label := range labels {
+		labelCount := 0
+		labelFrequency := map[string]int{}
+		for i, e := range examples {
+			if !slices.Contains(e.Labels, label) {
+				continue
+			}
+
+			labelCount++
+			for token := range tokensPerExample[i] {
+				labelFrequency[token]++
+			}
+		}
+
+		otherCount := len(examples) - labelCount
+		if labelCount == 0 {
+			continue
+		}
+
+		type candidate struct {
+			token string
+			score float64
+		}
+
+		var candidates []candidate
+		for token, frequency := range labelFrequency {
+			if frequency < opts.MinSupport {
+				continue
+			}
+
+			score := logOdds(frequency, labelCount) - logOdds(totalFrequency[token]-frequency, otherCount)
+			if score <= 0 {
+				continue
+			}
+
+			candidates = append(candidates, candidate{token: token, score: score})
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].score != candidates[j].score {
+				return candidates[i].score > candidates[j].score
+			}
+			return candidates[i].token < candidates[j].token
+		})
+
+		if len(candidates) > opts.MaxMatchers {
+			candidates = candidates[:opts.MaxMatchers]
+		}
+
+		var matchers []Matcher
+		for _, c := range candidates {
+			matchers = append(matchers, Matcher{
+				RegexStr: `(?i)\b` + regexp.QuoteMeta(c.token) + `\b`,
+				Weight:   suggestedWeight(c.score),
+			})
+		}
+
+		if len(matchers) > 0 {
+			cfg.Labels[label] = Label{Matchers: matchers}
+		}
+	}
+
+	return cfg
+}
+
+// logOdds returns the smoothed log-odds of a token appearing in count out of total examples.
+func logOdds(count, total int) float64 {
+	p := (float64(count) + suggestionPrior) / (float64(total) + 2*suggestionPrior)
+	return math.Log(p / (1 - p))
+}
+
+func suggestedWeight(score float64) int {
+	w := int(math.Round(score))
+	if w < 1 {
+		return 1
+	}
+	if w > maxSuggestedWeight {
+		return maxSuggestedWeight
+	}
+	return w
+}
+
+// tokenize returns the set of words of a text, see words for details.
+func tokenize(text string) map[string]struct{} {
+	tokens := map[string]struct{}{}
+	for _, w := range words(text) {
+		tokens[w] = struct{}{}
+	}
+	return tokens
+}
+
+// tokenCounts returns how often each word occurs in a text, see words for details.
+func tokenCounts(text string) map[string]int {
+	counts := map[string]int{}
+	for _, w := range words(text) {
+		counts[w]++
+	}
+	return counts
+}
+
+// words returns the lower cased words of a text, ignoring stop words, numbers and words shorter than 3 characters.
+func words(text string) []string {
+	var result []string
+
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+
+	for _, w := range fields {
+		w = strings.Trim(w, "-_")
+		if len(w) < 3 {
+			continue
+		}
+		if _, ok := stopWords[w]; ok {
+			continue
+		}
+		if strings.IndexFunc(w, unicode.IsLetter) == -1 {
+			continue
+		}
+		result = append(result, w)
+	}
+
+	return result
+}
+
+func exampleLabels(examples []Example) []string {
+	set := map[string]struct{}{}
+	for _, e := range examples {
+		for _, l := range e.Labels {
+			set[l] = struct{}{}
+		}
+	}
+
+	labels := make([]string, 0, len(set))
+	for l := range set {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	return labels
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("The Querier is slow: see https://example.com/x, 1234 out-of-order samples!")
+	require.Equal(t, map[string]struct{}{
+		"querier":      {},
+		"slow":         {},
+		"see":          {},
+		"example":      {},
+		"com":          {},
+		"out-of-order": {},
+		"samples":      {},
+	}, tokens)
+}
+
+func TestSuggestMatchers(t *testing.T) {
+	examples := []Example{
+		{Title: "querier crashes", Body: "the querier panics", Labels: []string{"query"}},
+		{Title: "slow querier", Labels: []string{"query"}},
+		{Title: "querier memory", Body: "ingester fine", Labels: []string{"query"}},
+		{Title: "ingester crashes", Labels: []string{"ingest"}},
+		{Title: "ingester out-of-order samples", Labels: []string{"ingest"}},
+		{Title: "ingester memory", Labels: []string{"ingest", "bug"}},
+	}
+
+	cfg := SuggestMatchers(examples, SuggestOptions{Labels: []string{"query", "ingest"}, MaxMatchers: 1})
+	require.Len(t, cfg.Labels, 2)
+	require.Equal(t, []Matcher{{RegexStr: `(?i)\bquerier\b`, Weight: 4}}, cfg.Labels["query"].Matchers)
+	require.Equal(t, []Matcher{{RegexStr: `(?i)\bingester\b`, Weight: 2}}, cfg.Labels["ingest"].Matchers)
+
+	// suggestions need to be usable as config
+	raw, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	parsed, err := ParseConfig(raw)
+	require.NoError(t, err)
+	require.NoError(t, parsed.Validate())
+	require.True(t, parsed.Labels["query"].Matchers[0].regex.MatchString("Querier is down"))
+}
+
+func TestSuggestMatchers_AllLabelsByDefault(t *testing.T) {
+	examples := []Example{
+		{Title: "querier crashes", Labels: []string{"query"}},
+		{Title: "querier slow", Labels: []string{"query"}},
+		{Title: "ingester crashes", Labels: []string{"ingest"}},
+	}
+
+	// "ingest" has no token with enough support, so only "query" gets suggestions
+	cfg := SuggestMatchers(examples, SuggestOptions{})
+	require.Len(t, cfg.Labels, 1)
+	require.Contains(t, cfg.Labels, "query")
+}
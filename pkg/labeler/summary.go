@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+)
+
+// summaryMarkdown renders the scores of the labels and the chosen label as section of the job summary.
+// Only labels with a score are listed, label is empty if none has been chosen.
+func summaryMarkdown(number int, scores []LabelScore, label string, dryRun bool) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "### Label of #%d\n\n", number)
+
+	var rows [][]string
+	for _, s := range scores {
+		if s.Score == 0 {
+			continue
+		}
+
+		matched := make([]string, len(s.Matched))
+		for i, m := range s.Matched {
+			matched[i] = "`" + m + "`"
+		}
+		rows = append(rows, []string{s.Label, strconv.FormatFloat(s.Score, 'g', 4, 64), strings.Join(matched, ", ")})
+	}
+
+	if len(rows) > 0 {
+		sb.WriteString(githubaction.MarkdownTable([]string{"Label", "Score", "Matched"}, rows))
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("No label has a score.\n\n")
+	}
+
+	if label != "" {
+		fmt.Fprintf(&sb, "Chosen label: **%s**\n", label)
+	} else {
+		sb.WriteString("No label has been chosen.\n")
+	}
+
+	if dryRun {
+		sb.WriteString("\nDry-run is enabled, the issue hasn't been changed.\n")
+	}
+
+	return sb.String()
+}
+
+// skippedMarkdown renders why the issue hasn't been labeled as section of the job summary.
+func skippedMarkdown(number int, reason string) string {
+	return fmt.Sprintf("### Label of #%d\n\nSkipped, as %s.\n", number, reason)
+}
+
+// skip reports why the issue isn't labeled in the job summary and the skipped-reason output.
+func (l *Labeler) skip(iss issue.Issue, reason string) {
+	l.writeSummary(skippedMarkdown(iss.Number, reason))
+
+	if err := githubaction.SetOutput("skipped-reason", reason); err != nil {
+		level.Warn(l.logger).Log("msg", "unable to set outputs", "err", err)
+	}
+}
+
+// writeSummary appends markdown to the job summary of the workflow run.
+func (l *Labeler) writeSummary(markdown string) {
+	if err := githubaction.AppendSummary(markdown); err != nil {
+		level.Warn(l.logger).Log("msg", "unable to write job summary", "err", err)
+	}
+}
@@ -18,34 +18,49 @@ package labeler
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/google/go-github/github"
 )
 
 func getLabelAssigner(gh *github.Client) labelAssigner {
-	return func(repoOwner, repoName string, issueNumber int, labels []string) error {
-		_, _, err := gh.Issues.ReplaceLabelsForIssue(context.Background(), repoOwner, repoName, issueNumber, labels)
-		return err
-	}
-}
+	return func(repoOwner, repoName string, issueNumber int, add, remove []string) error {
+		ctx := context.Background()
+
+		// Labels are only added and removed individually, so that labels changed by others since the event was triggered
+		// are preserved. They are added before others are removed, so the issue never ends up without any of them.
+		_, _, err := gh.Issues.AddLabelsToIssue(ctx, repoOwner, repoName, issueNumber, add)
+		if err != nil {
+			return fmt.Errorf("unable to add labels, due %w", err)
+		}
 
-func getIssueLabels(issue *github.Issue) []string {
-	issueLabels := make([]string, len(issue.Labels))
+		for _, label := range remove {
+			if slices.Contains(add, label) {
+				continue
+			}
 
-	for issueLabelIdx, issueLabel := range issue.Labels {
-		if issueLabel.Name == nil {
-			continue
+			resp, err := gh.Issues.RemoveLabelForIssue(ctx, repoOwner, repoName, issueNumber, label)
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				// the issue doesn't carry this label (anymore)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("unable to remove label %q, due %w", label, err)
+			}
 		}
-		issueLabels[issueLabelIdx] = *issueLabel.Name
-	}
 
-	return issueLabels
+		return nil
+	}
 }
 
-func decomposeRepoURL(repoURL string) (repoOwner, repoName string) {
-	repoUrlSplit := strings.Split(repoURL, "/")
-	repoName = repoUrlSplit[len(repoUrlSplit)-1]
-	repoOwner = repoUrlSplit[len(repoUrlSplit)-2]
-	return repoOwner, repoName
+func getDryRunLabelAssigner(logger log.Logger) labelAssigner {
+	return func(repoOwner, repoName string, issueNumber int, add, remove []string) error {
+		level.Info(logger).Log("msg", "dry-run is enabled, not changing labels of issue", "issue", issueNumber, "add", strings.Join(add, ", "), "remove", strings.Join(remove, ", "))
+		return nil
+	}
 }
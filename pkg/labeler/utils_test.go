@@ -17,57 +17,42 @@
 package labeler
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/google/go-github/github"
 	"github.com/stretchr/testify/require"
 )
 
-func TestDecomposeRepoURL(t *testing.T) {
-	testCases := []struct {
-		url           string
-		expectedOwner string
-		expectedName  string
-	}{
-		{
-			url:           "https://api.github.com/repos/grafana/grafana",
-			expectedOwner: "grafana",
-			expectedName:  "grafana",
-		},
-		{
-			url:           "https://api.github.com/repos/my-org/my-repo",
-			expectedOwner: "my-org",
-			expectedName:  "my-repo",
-		},
-		{
-			url:           "/repos/owner/repo",
-			expectedOwner: "owner",
-			expectedName:  "repo",
-		},
-	}
+func TestGetLabelAssigner(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
 
-	for _, tc := range testCases {
-		t.Run(tc.url, func(t *testing.T) {
-			owner, name := decomposeRepoURL(tc.url)
-			require.Equal(t, tc.expectedOwner, owner)
-			require.Equal(t, tc.expectedName, name)
-		})
-	}
-}
-
-func TestGetIssueLabels(t *testing.T) {
-	t.Run("returns label names", func(t *testing.T) {
-		issue := &github.Issue{
-			Labels: []github.Label{
-				{Name: github.String("bug")},
-				{Name: github.String("enhancement")},
-			},
+		if r.Method == http.MethodDelete && r.URL.Path == "/repos/owner/repo/issues/1/labels/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Label does not exist"}`))
+			return
 		}
-		require.Equal(t, []string{"bug", "enhancement"}, getIssueLabels(issue))
-	})
 
-	t.Run("returns empty slice for issue with no labels", func(t *testing.T) {
-		issue := &github.Issue{Labels: []github.Label{}}
-		require.Empty(t, getIssueLabels(issue))
-	})
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(server.URL + "/")
+
+	assign := getLabelAssigner(gh)
+	require.NoError(t, assign("owner", "repo", 1, []string{"squad-a"}, []string{"missing", "needs-triage", "squad-a"}))
+
+	// the issue's labels are never read and replaced, so concurrent changes of other labels are kept
+	require.Equal(t, []string{
+		"POST /repos/owner/repo/issues/1/labels [\"squad-a\"]\n",
+		"DELETE /repos/owner/repo/issues/1/labels/missing ",
+		"DELETE /repos/owner/repo/issues/1/labels/needs-triage ",
+	}, requests)
 }
@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package labelexpr implements boolean expressions over the labels of an issue, shared by the configs of all actions.
+package labelexpr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Expr is a boolean expression over labels. It is either a single label or a combination of nested expressions.
+//
+// In yaml an expression can be written as
+//   - a single label: `bug`
+//   - a list of expressions (legacy form), which matches if **any** of them matches: `[bug, regression]`
+//   - a mapping with the keys `all`, `any` and `none`, each containing a list of expressions:
+//     `{all: [bug, {any: [loki, mimir]}], none: [stale]}`. All keys which are set need to match.
+//
+// An empty expression matches every set of labels.
+type Expr struct {
+	// Label matches if the label is present. If set, all other fields are ignored.
+	Label string
+
+	// All matches if all of the nested expressions match.
+	All []Expr
+
+	// Any matches if at least one of the nested expressions matches.
+	Any []Expr
+
+	// None matches if none of the nested expressions match.
+	None []Expr
+}
+
+// Label returns an expression matching a single label.
+func Label(label string) Expr {
+	return Expr{Label: label}
+}
+
+// AnyOf returns an expression which matches if at least one of the labels is present.
+func AnyOf(labels ...string) Expr {
+	return Expr{Any: labelExprs(labels)}
+}
+
+// AllOf returns an expression which matches if all of the labels are present.
+func AllOf(labels ...string) Expr {
+	return Expr{All: labelExprs(labels)}
+}
+
+// NoneOf returns an expression which matches if none of the labels are present.
+func NoneOf(labels ...string) Expr {
+	return Expr{None: labelExprs(labels)}
+}
+
+func labelExprs(labels []string) []Expr {
+	exprs := make([]Expr, len(labels))
+	for i, l := range labels {
+		exprs[i] = Label(l)
+	}
+	return exprs
+}
+
+// IsZero reports whether the expression is empty and therefore matches every set of labels.
+func (e Expr) IsZero() bool {
+	return e.Label == "" && len(e.All) == 0 && len(e.Any) == 0 && len(e.None) == 0
+}
+
+// Match reports whether the expression matches the given labels.
+func (e Expr) Match(labels []string) bool {
+	if e.Label != "" {
+		return slices.Contains(labels, e.Label)
+	}
+
+	for _, sub := range e.All {
+		if !sub.Match(labels) {
+			return false
+		}
+	}
+
+	if len(e.Any) > 0 {
+		match := false
+		for _, sub := range e.Any {
+			if sub.Match(labels) {
+				match = true
+				break
+			}
+		}
+
+		if !match {
+			return false
+		}
+	}
+
+	for _, sub := range e.None {
+		if sub.Match(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Labels returns all labels referenced by the expression, in order of appearance.
+func (e Expr) Labels() []string {
+	if e.Label != "" {
+		return []string{e.Label}
+	}
+
+	var labels []string
+	for _, group := range [][]Expr{e.All, e.Any, e.None} {
+		for _, sub := range group {
+			for _, l := range sub.Labels() {
+				if !slices.Contains(labels, l) {
+					labels = append(labels, l)
+				}
+			}
+		}
+	}
+	return labels
+}
+
+// String returns a human readable representation of the expression, e.g. `all(bug, any(loki, mimir))`.
+func (e Expr) String() string {
+	if e.Label != "" {
+		return e.Label
+	}
+
+	if e.IsZero() {
+		return "*"
+	}
+
+	var parts []string
+	for _, group := range []struct {
+		name  string
+		exprs []Expr
+	}{{"all", e.All}, {"any", e.Any}, {"none", e.None}} {
+		if len(group.exprs) == 0 {
+			continue
+		}
+
+		subs := make([]string, len(group.exprs))
+		for i, sub := range group.exprs {
+			subs[i] = sub.String()
+		}
+		parts = append(parts, fmt.Sprintf("%s(%s)", group.name, strings.Join(subs, ", ")))
+	}
+
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "all(" + strings.Join(parts, ", ") + ")"
+}
+
+// exprMapping is the yaml mapping form of an expression
+type exprMapping struct {
+	All  []Expr `yaml:"all,omitempty"`
+	Any  []Expr `yaml:"any,omitempty"`
+	None []Expr `yaml:"none,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler and accepts all forms described in Expr.
+func (e *Expr) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	switch raw.(type) {
+	case nil:
+		*e = Expr{}
+		return nil
+	case []interface{}:
+		var list []Expr
+		if err := unmarshal(&list); err != nil {
+			return err
+		}
+		*e = Expr{Any: list}
+		return nil
+	case map[interface{}]interface{}:
+		var m exprMapping
+		if err := unmarshal(&m); err != nil {
+			return err
+		}
+
+		if len(m.All) == 0 && len(m.Any) == 0 && len(m.None) == 0 {
+			return errors.New("label expression mapping requires at least one of the keys all, any and none")
+		}
+
+		*e = Expr{All: m.All, Any: m.Any, None: m.None}
+		return nil
+	default:
+		// scalars like numbers are valid label names as well
+		*e = Label(fmt.Sprint(raw))
+		return nil
+	}
+}
+
+// MarshalYAML implements yaml.Marshaler and uses the most compact form which represents the expression.
+func (e Expr) MarshalYAML() (interface{}, error) {
+	if e.Label != "" {
+		return e.Label, nil
+	}
+
+	if len(e.All) == 0 && len(e.None) == 0 {
+		return e.Any, nil
+	}
+
+	return exprMapping{All: e.All, Any: e.Any, None: e.None}, nil
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelexpr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalYAML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      string
+		expected Expr
+	}{
+		{
+			name:     "single label",
+			raw:      `bug`,
+			expected: Label("bug"),
+		},
+		{
+			name:     "numeric label",
+			raw:      `123`,
+			expected: Label("123"),
+		},
+		{
+			name:     "legacy list means any",
+			raw:      `[bug, regression]`,
+			expected: AnyOf("bug", "regression"),
+		},
+		{
+			name: "nested mapping",
+			raw: `
+all:
+  - bug
+  - any: [loki, mimir]
+none: [stale]`,
+			expected: Expr{
+				All:  []Expr{Label("bug"), AnyOf("loki", "mimir")},
+				None: []Expr{Label("stale")},
+			},
+		},
+		{
+			name:     "empty",
+			raw:      `~`,
+			expected: Expr{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var e Expr
+			require.NoError(t, yaml.Unmarshal([]byte(tc.raw), &e))
+			require.Equal(t, tc.expected, e)
+		})
+	}
+}
+
+func TestUnmarshalYAML_Invalid(t *testing.T) {
+	var e Expr
+	require.Error(t, yaml.Unmarshal([]byte(`{foo: [bar]}`), &e))
+	require.Error(t, yaml.Unmarshal([]byte(`{all: {any: bug}}`), &e))
+}
+
+func TestMarshalYAML_RoundTrip(t *testing.T) {
+	for _, e := range []Expr{
+		Label("bug"),
+		AnyOf("bug", "regression"),
+		{All: []Expr{Label("bug"), AnyOf("loki", "mimir")}, None: []Expr{Label("stale")}},
+	} {
+		raw, err := yaml.Marshal(e)
+		require.NoError(t, err)
+
+		var parsed Expr
+		require.NoError(t, yaml.Unmarshal(raw, &parsed))
+		require.Equal(t, e, parsed)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	expr := Expr{
+		All:  []Expr{Label("bug"), AnyOf("loki", "mimir")},
+		None: []Expr{Label("stale")},
+	}
+
+	testCases := []struct {
+		name     string
+		expr     Expr
+		labels   []string
+		expected bool
+	}{
+		{name: "empty matches everything", expr: Expr{}, labels: nil, expected: true},
+		{name: "label present", expr: Label("bug"), labels: []string{"bug"}, expected: true},
+		{name: "label missing", expr: Label("bug"), labels: []string{"feature"}, expected: false},
+		{name: "any of", expr: AnyOf("a", "b"), labels: []string{"b"}, expected: true},
+		{name: "any of none present", expr: AnyOf("a", "b"), labels: []string{"c"}, expected: false},
+		{name: "all of", expr: AllOf("a", "b"), labels: []string{"b", "a"}, expected: true},
+		{name: "all of one missing", expr: AllOf("a", "b"), labels: []string{"a"}, expected: false},
+		{name: "none of", expr: NoneOf("a", "b"), labels: []string{"c"}, expected: true},
+		{name: "none of one present", expr: NoneOf("a", "b"), labels: []string{"b"}, expected: false},
+		{name: "nested match", expr: expr, labels: []string{"bug", "mimir"}, expected: true},
+		{name: "nested any missing", expr: expr, labels: []string{"bug"}, expected: false},
+		{name: "nested none present", expr: expr, labels: []string{"bug", "loki", "stale"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.expr.Match(tc.labels))
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	require.Equal(t, "*", Expr{}.String())
+	require.Equal(t, "any(a, b)", AnyOf("a", "b").String())
+	require.Equal(t, "all(all(bug, any(loki, mimir)), none(stale))", Expr{
+		All:  []Expr{Label("bug"), AnyOf("loki", "mimir")},
+		None: []Expr{Label("stale")},
+	}.String())
+}
+
+func TestLabels(t *testing.T) {
+	e := Expr{
+		All:  []Expr{Label("bug"), AnyOf("loki", "bug")},
+		None: []Expr{Label("stale")},
+	}
+	require.Equal(t, []string{"bug", "loki", "stale"}, e.Labels())
+}
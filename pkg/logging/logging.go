@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging creates the structured loggers shared by both actions.
+package logging
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	// FormatLogfmt writes one logfmt line per log entry. This is the default.
+	FormatLogfmt = "logfmt"
+
+	// FormatJSON writes one JSON object per log entry.
+	FormatJSON = "json"
+
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// New creates a logger writing to w in the given format, which drops entries below the given level.
+// An empty format defaults to FormatLogfmt and an empty level to LevelInfo.
+func New(w io.Writer, format, lvl string) (log.Logger, error) {
+	var logger log.Logger
+	switch format {
+	case "", FormatLogfmt:
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(w))
+	case FormatJSON:
+		logger = log.NewJSONLogger(log.NewSyncWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %q or %q", format, FormatLogfmt, FormatJSON)
+	}
+
+	option, err := levelOption(lvl)
+	if err != nil {
+		return nil, err
+	}
+
+	return level.NewFilter(logger, option), nil
+}
+
+func levelOption(lvl string) (level.Option, error) {
+	switch lvl {
+	case LevelDebug:
+		return level.AllowDebug(), nil
+	case "", LevelInfo:
+		return level.AllowInfo(), nil
+	case LevelWarn:
+		return level.AllowWarn(), nil
+	case LevelError:
+		return level.AllowError(), nil
+	default:
+		return nil, fmt.Errorf("unknown log level %q, expected %q, %q, %q or %q", lvl, LevelDebug, LevelInfo, LevelWarn, LevelError)
+	}
+}
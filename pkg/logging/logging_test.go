@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, LevelInfo)
+	require.NoError(t, err)
+
+	level.Debug(logger).Log("msg", "hidden")
+	level.Info(logger).Log("msg", "shown", "member", "alice")
+	require.JSONEq(t, `{"level": "info", "msg": "shown", "member": "alice"}`, buf.String())
+
+	buf.Reset()
+	logger, err = New(&buf, "", LevelDebug)
+	require.NoError(t, err)
+
+	level.Debug(logger).Log("msg", "shown")
+	require.Equal(t, "level=debug msg=shown\n", buf.String())
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "xml", "")
+	require.Error(t, err)
+
+	_, err = New(&bytes.Buffer{}, "", "verbose")
+	require.Error(t, err)
+}
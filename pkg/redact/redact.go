@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact removes secrets and personal data from log output of the actions.
+package redact
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	githubaction "github.com/grafana/escalation-scheduler/pkg/github-action"
+)
+
+const (
+	// ModeNone logs everything as it is.
+	ModeNone = "none"
+
+	// ModeSecrets redacts secrets, i.e. credentials and tokens in URLs and all values registered with Mask. This is the default.
+	ModeSecrets = "secrets"
+
+	// ModeStrict redacts secrets and additionally omits personal data like issue titles and the times of calendar events.
+	ModeStrict = "strict"
+
+	// placeholder replaces redacted values
+	placeholder = "REDACTED"
+
+	// minTokenLength is the length from which path segments of URLs are considered tokens if they mix letters and digits
+	minTokenLength = 16
+)
+
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// Redactor redacts secrets and personal data from strings. It's safe for concurrent use.
+type Redactor struct {
+	mode string
+
+	mtx     sync.RWMutex
+	secrets []string
+}
+
+// New returns a redactor for the given mode. An empty mode defaults to ModeSecrets.
+func New(mode string) (*Redactor, error) {
+	switch mode {
+	case "":
+		mode = ModeSecrets
+	case ModeNone, ModeSecrets, ModeStrict:
+	default:
+		return nil, fmt.Errorf("unknown log redaction %q, expected %q, %q or %q", mode, ModeNone, ModeSecrets, ModeStrict)
+	}
+
+	return &Redactor{mode: mode}, nil
+}
+
+// Mask registers a secret, which is redacted from all strings from now on. The secret is masked in the log of the
+// workflow run as well, regardless of the mode, so that it's also hidden from output not passing the redactor.
+func (r *Redactor) Mask(secret string) {
+	if secret == "" {
+		return
+	}
+
+	githubaction.AddMask(secret)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.secrets = append(r.secrets, secret)
+}
+
+// String redacts all secrets from s.
+func (r *Redactor) String(s string) string {
+	if r.mode == ModeNone {
+		return s
+	}
+
+	r.mtx.RLock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, placeholder)
+	}
+	r.mtx.RUnlock()
+
+	return urlPattern.ReplaceAllStringFunc(s, redactURL)
+}
+
+// Personal returns s unless personal data is omitted, e.g. issue titles.
+func (r *Redactor) Personal(s string) string {
+	if r.mode == ModeStrict {
+		return "(omitted)"
+	}
+	return s
+}
+
+// redactURL removes credentials, the query and path segments which look like tokens from a URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return placeholder
+	}
+
+	if u.User != nil {
+		u.User = url.User(placeholder)
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = placeholder
+	}
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if isToken(segment) {
+			segments[i] = placeholder
+		}
+	}
+	// keep the escaping of the original path
+	u.RawPath = strings.Join(segments, "/")
+	u.Path, _ = url.PathUnescape(u.RawPath)
+
+	return u.String()
+}
+
+// isToken reports whether a path segment looks like a secret, e.g. "private-0a1b2c..." of private calendar feeds,
+// or a long random string of letters and digits.
+func isToken(segment string) bool {
+	if strings.HasPrefix(segment, "private-") {
+		return true
+	}
+
+	if len(segment) < minTokenLength {
+		return false
+	}
+
+	var letters, digits bool
+	for _, c := range segment {
+		switch {
+		case unicode.IsLetter(c):
+			letters = true
+		case unicode.IsDigit(c):
+			digits = true
+		case c != '-' && c != '_':
+			// e.g. an email address or a file name
+			return false
+		}
+	}
+	return letters && digits
+}
+
+// Writer returns a writer redacting everything written to w, meant to be used as output of a logger.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return writer{w: w, r: r}
+}
+
+type writer struct {
+	w io.Writer
+	r *Redactor
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Logger returns a logger redacting all string and error values before passing them to next.
+// Levels are passed on unchanged, so that next can still filter by them.
+func (r *Redactor) Logger(next log.Logger) log.Logger {
+	return log.LoggerFunc(func(keyvals ...interface{}) error {
+		redacted := make([]interface{}, len(keyvals))
+		copy(redacted, keyvals)
+
+		for i := 1; i < len(redacted); i += 2 {
+			switch v := redacted[i].(type) {
+			case level.Value:
+			case string:
+				redacted[i] = r.String(v)
+			case error:
+				redacted[i] = r.String(v.Error())
+			case fmt.Stringer:
+				redacted[i] = r.String(v.String())
+			}
+		}
+		return next.Log(redacted...)
+	})
+}
+
+var (
+	defaultMtx      sync.RWMutex
+	defaultRedactor = &Redactor{mode: ModeSecrets}
+)
+
+// SetDefault sets the redactor used by the package level functions.
+func SetDefault(r *Redactor) {
+	defaultMtx.Lock()
+	defer defaultMtx.Unlock()
+	defaultRedactor = r
+}
+
+// Default returns the redactor used by the package level functions, which redacts secrets unless SetDefault is called.
+func Default() *Redactor {
+	defaultMtx.RLock()
+	defer defaultMtx.RUnlock()
+	return defaultRedactor
+}
+
+// Mask registers a secret with the default redactor, see Redactor.Mask.
+func Mask(secret string) {
+	Default().Mask(secret)
+}
+
+// Personal omits personal data according to the default redactor, see Redactor.Personal.
+func Personal(s string) string {
+	return Default().Personal(s)
+}
+
+// String redacts secrets according to the default redactor, see Redactor.String.
+func String(s string) string {
+	return Default().String(s)
+}
@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rules implements named rule blocks which match issues by their properties.
+// Both actions use them to decide whether and how they handle an issue.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+)
+
+const (
+	// TypeIssue matches issues only.
+	TypeIssue = "issue"
+
+	// TypePullRequest matches pull requests only.
+	TypePullRequest = "pull_request"
+)
+
+// Rule matches issues by their properties. All conditions which are set need to match, an empty rule matches every issue.
+type Rule struct {
+	// Labels is a label expression the issue needs to match.
+	Labels labelexpr.Expr `yaml:"labels,omitempty"`
+
+	// AuthorAssociation is a list of associations of which the author of the issue needs to have any, e.g. MEMBER or CONTRIBUTOR.
+	AuthorAssociation []string `yaml:"authorAssociation,omitempty"`
+
+	// Type restricts the rule to either "issue" or "pull_request".
+	Type string `yaml:"type,omitempty"`
+
+	// Milestone is a regular expression the title of the milestone needs to match. Issues without milestone are matched as empty string.
+	Milestone string `yaml:"milestone,omitempty"`
+
+	// Title is a regular expression the title of the issue needs to match.
+	Title string `yaml:"title,omitempty"`
+
+	// Body is a regular expression the body of the issue needs to match.
+	Body string `yaml:"body,omitempty"`
+
+	// CreatedAfter and CreatedBefore restrict the creation time of the issue.
+	CreatedAfter  *time.Time `yaml:"createdAfter,omitempty"`
+	CreatedBefore *time.Time `yaml:"createdBefore,omitempty"`
+
+	// MaxAge restricts the rule to issues created within the given duration.
+	MaxAge time.Duration `yaml:"maxAge,omitempty"`
+
+	// Any and None combine nested rules: at least one of the rules in Any and none of the rules in None need to match.
+	Any  []Rule `yaml:"any,omitempty"`
+	None []Rule `yaml:"none,omitempty"`
+
+	milestone *regexp.Regexp `yaml:"-"`
+	title     *regexp.Regexp `yaml:"-"`
+	body      *regexp.Regexp `yaml:"-"`
+}
+
+// Validate checks the rule and compiles its regular expressions. It needs to be called before matching issues.
+func (r *Rule) Validate() (err error) {
+	switch r.Type {
+	case "", TypeIssue, TypePullRequest:
+	default:
+		return fmt.Errorf("unknown type %q, expected %q or %q", r.Type, TypeIssue, TypePullRequest)
+	}
+
+	if r.milestone, err = compileOptional(r.Milestone); err != nil {
+		return fmt.Errorf("invalid milestone regex, due %w", err)
+	}
+	if r.title, err = compileOptional(r.Title); err != nil {
+		return fmt.Errorf("invalid title regex, due %w", err)
+	}
+	if r.body, err = compileOptional(r.Body); err != nil {
+		return fmt.Errorf("invalid body regex, due %w", err)
+	}
+
+	// the call to Validate() modifies the rule, so we call it on the rule inside the slice.
+	for idx := range r.Any {
+		if err := r.Any[idx].Validate(); err != nil {
+			return err
+		}
+	}
+	for idx := range r.None {
+		if err := r.None[idx].Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func compileOptional(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+// Match reports whether the issue matches the rule at the given time.
+func (r *Rule) Match(i issue.Issue, now time.Time) bool {
+	if !r.Labels.Match(i.Labels) {
+		return false
+	}
+
+	if len(r.AuthorAssociation) > 0 && !slices.ContainsFunc(r.AuthorAssociation, func(a string) bool {
+		return strings.EqualFold(a, i.AuthorAssociation)
+	}) {
+		return false
+	}
+
+	switch r.Type {
+	case TypeIssue:
+		if i.IsPullRequest {
+			return false
+		}
+	case TypePullRequest:
+		if !i.IsPullRequest {
+			return false
+		}
+	}
+
+	if r.milestone != nil && !r.milestone.MatchString(i.Milestone) {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(i.Title) {
+		return false
+	}
+	if r.body != nil && !r.body.MatchString(i.Body) {
+		return false
+	}
+
+	if r.CreatedAfter != nil && !i.CreatedAt.After(*r.CreatedAfter) {
+		return false
+	}
+	if r.CreatedBefore != nil && !i.CreatedAt.Before(*r.CreatedBefore) {
+		return false
+	}
+	if r.MaxAge > 0 && now.Sub(i.CreatedAt) > r.MaxAge {
+		return false
+	}
+
+	if len(r.Any) > 0 && !slices.ContainsFunc(r.Any, func(sub Rule) bool { return sub.Match(i, now) }) {
+		return false
+	}
+
+	for _, sub := range r.None {
+		if sub.Match(i, now) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Set is a collection of named rules, which can be referenced by name from other parts of a config.
+type Set map[string]Rule
+
+// Validate validates all rules of the set.
+func (s Set) Validate() error {
+	for name, r := range s {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("invalid rule %q: %w", name, err)
+		}
+
+		// the call to Validate() modifies the rule, so we need to put the result back.
+		s[name] = r
+	}
+	return nil
+}
+
+// CheckRef returns an error if a rule is referenced which doesn't exist. An empty reference is valid.
+func (s Set) CheckRef(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := s[name]; !ok {
+		return fmt.Errorf("rule %q is referenced, but not defined", name)
+	}
+	return nil
+}
+
+// Match reports whether the issue matches the rule with the given name. An empty name matches every issue,
+// unknown names match none, see CheckRef.
+func (s Set) Match(name string, i issue.Issue, now time.Time) bool {
+	if name == "" {
+		return true
+	}
+
+	r, ok := s[name]
+	if !ok {
+		return false
+	}
+	return r.Match(i, now)
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/escalation-scheduler/pkg/issue"
+	"github.com/grafana/escalation-scheduler/pkg/labelexpr"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseRules(t *testing.T) {
+	raw := `
+external-loki-bugs:
+  labels: bug
+  authorAssociation: [NONE, CONTRIBUTOR]
+  type: issue
+  body: '(?i)loki'
+  maxAge: 24h
+  createdAfter: 2024-01-01T00:00:00Z
+  none:
+    - milestone: '^v1\.'
+`
+	var set Set
+	require.NoError(t, yaml.Unmarshal([]byte(raw), &set))
+	require.NoError(t, set.Validate())
+
+	r := set["external-loki-bugs"]
+	require.Equal(t, labelexpr.Label("bug"), r.Labels)
+	require.Equal(t, []string{"NONE", "CONTRIBUTOR"}, r.AuthorAssociation)
+	require.Equal(t, 24*time.Hour, r.MaxAge)
+	require.Equal(t, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), r.CreatedAfter.UTC())
+	require.Len(t, r.None, 1)
+}
+
+func TestValidate_Invalid(t *testing.T) {
+	for name, r := range map[string]Rule{
+		"unknown type":          {Type: "discussion"},
+		"invalid title":         {Title: "[invalid"},
+		"invalid nested":        {Any: []Rule{{Body: "[invalid"}}},
+		"invalid nested (none)": {None: []Rule{{Milestone: "[invalid"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, r.Validate())
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	base := issue.Issue{
+		Title:             "Loki crashes",
+		Body:              "the querier panics",
+		Labels:            []string{"bug"},
+		Milestone:         "v2.0",
+		AuthorAssociation: "CONTRIBUTOR",
+		CreatedAt:         now.Add(-time.Hour),
+	}
+
+	before := now.Add(-30 * time.Minute)
+	after := now.Add(-2 * time.Hour)
+
+	testCases := []struct {
+		name     string
+		rule     Rule
+		expected bool
+	}{
+		{name: "empty rule", rule: Rule{}, expected: true},
+		{name: "labels", rule: Rule{Labels: labelexpr.AllOf("bug")}, expected: true},
+		{name: "labels mismatch", rule: Rule{Labels: labelexpr.AllOf("feature")}, expected: false},
+		{name: "author association is case insensitive", rule: Rule{AuthorAssociation: []string{"member", "contributor"}}, expected: true},
+		{name: "author association mismatch", rule: Rule{AuthorAssociation: []string{"MEMBER"}}, expected: false},
+		{name: "type issue", rule: Rule{Type: TypeIssue}, expected: true},
+		{name: "type pull request", rule: Rule{Type: TypePullRequest}, expected: false},
+		{name: "milestone", rule: Rule{Milestone: `^v2\.`}, expected: true},
+		{name: "title", rule: Rule{Title: `(?i)loki`}, expected: true},
+		{name: "body mismatch", rule: Rule{Body: `ingester`}, expected: false},
+		{name: "created after", rule: Rule{CreatedAfter: &after}, expected: true},
+		{name: "created before", rule: Rule{CreatedBefore: &before}, expected: true},
+		{name: "created before mismatch", rule: Rule{CreatedBefore: &after}, expected: false},
+		{name: "max age", rule: Rule{MaxAge: 2 * time.Hour}, expected: true},
+		{name: "max age exceeded", rule: Rule{MaxAge: 30 * time.Minute}, expected: false},
+		{name: "any", rule: Rule{Any: []Rule{{Title: "mimir"}, {Title: "Loki"}}}, expected: true},
+		{name: "any mismatch", rule: Rule{Any: []Rule{{Title: "mimir"}, {Title: "tempo"}}}, expected: false},
+		{name: "none", rule: Rule{None: []Rule{{AuthorAssociation: []string{"MEMBER"}}}}, expected: true},
+		{name: "none mismatch", rule: Rule{None: []Rule{{Labels: labelexpr.Label("bug")}}}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.rule.Validate())
+			require.Equal(t, tc.expected, tc.rule.Match(base, now))
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	set := Set{"bugs": {Labels: labelexpr.Label("bug")}}
+	require.NoError(t, set.Validate())
+
+	require.NoError(t, set.CheckRef(""))
+	require.NoError(t, set.CheckRef("bugs"))
+	require.Error(t, set.CheckRef("unknown"))
+
+	bug := issue.Issue{Labels: []string{"bug"}}
+	require.True(t, set.Match("", issue.Issue{}, time.Now()))
+	require.True(t, set.Match("bugs", bug, time.Now()))
+	require.False(t, set.Match("bugs", issue.Issue{}, time.Now()))
+	require.False(t, set.Match("unknown", bug, time.Now()))
+}